@@ -1,29 +1,99 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/disgoorg/disgo"
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/config"
 	"github.com/makeitchaccha/ringring/internal/pkg/icommand"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"github.com/makeitchaccha/ringring/pkg/command"
 )
 
 func main() {
+	configPath := flag.String("config", "config.yml", "path to the config file")
+	dryRun := flag.Bool("dry-run", false, "print the changes without deploying")
+	remove := flag.Bool("remove", false, "remove the deployed commands instead of deploying")
+
+	guildIDs := make([]snowflake.ID, 0)
+	flag.Func("guild", "deploy to the guild instead of globally, can be repeated", func(s string) error {
+		id, err := snowflake.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid guild id: %w", err)
+		}
+		guildIDs = append(guildIDs, id)
+		return nil
+	})
+	flag.Parse()
+
 	// login discord bot and deploy commands
 	locale.Init("./locales")
-	client, err := disgo.New(os.Getenv("DISCORD_TOKEN"))
 
+	config, err := config.New(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		os.Exit(1)
+	}
+
+	client, err := disgo.New(config.Token)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, "failed to create client:", err)
+		os.Exit(1)
 	}
 
-	// deploy commands
 	commandManager := command.NewManager()
 	commandManager.Register(&icommand.Settings{})
+	commandManager.Register(&icommand.Stats{})
+	commandManager.Register(&icommand.History{})
+
+	opts := make([]command.DeployOpt, 0)
+	if *dryRun {
+		opts = append(opts, command.WithDryRun())
+	}
+
+	// global commands take a while to propagate, guild commands are available immediately
+	if len(guildIDs) == 0 {
+		if err := run(commandManager, client, "global", *remove, opts...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	for _, guildID := range guildIDs {
+		target := fmt.Sprintf("guild %s", guildID)
+		if err := run(commandManager, client, target, *remove, append(opts, command.WithGuild(guildID))...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("dry run, nothing is deployed")
+	}
+}
+
+func run(commandManager command.Manager, client bot.Client, target string, remove bool, opts ...command.DeployOpt) error {
+	deploy := commandManager.Deploy
+	if remove {
+		deploy = commandManager.Remove
+	}
 
-	if err := commandManager.Deploy(client); err != nil {
-		panic(err)
+	changes, err := deploy(client, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to deploy commands to %s: %w", target, err)
 	}
 
+	if len(changes) == 0 {
+		fmt.Printf("%s: commands are up to date\n", target)
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s: %s\n", target, change)
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+)
+
+// localecheck reports the missing, extra and mismatched keys of the locale files.
+func main() {
+	dir := flag.String("dir", "./locales", "path to the locales directory")
+	flag.Parse()
+
+	problems, err := locale.Check(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to check locales:", err)
+		os.Exit(1)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("no problems found")
+}
@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/makeitchaccha/ringring/internal/app/bot"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
 	"github.com/makeitchaccha/ringring/internal/pkg/config"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"gorm.io/gorm"
@@ -24,6 +25,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := cache.ConfigureAvatar(cache.AvatarConfig{
+		Size:     config.AvatarCacheSize,
+		TTL:      config.AvatarCacheTTL,
+		Dir:      config.AvatarCacheDir,
+		DiskSize: config.AvatarCacheDiskSize,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to configure avatar cache:", err)
+		os.Exit(1)
+	}
+
 	db, err := gorm.Open(config.Dialector, &gorm.Config{})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "failed to open database:", err)
@@ -16,14 +16,27 @@ import (
 	"github.com/golang/freetype/truetype"
 	"github.com/makeitchaccha/ringring/internal/pkg/call"
 	"github.com/makeitchaccha/ringring/internal/pkg/icommand"
+	"github.com/makeitchaccha/ringring/internal/pkg/iform"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"github.com/makeitchaccha/ringring/internal/pkg/rule"
 	"github.com/makeitchaccha/ringring/pkg/command"
 	"github.com/makeitchaccha/ringring/pkg/form"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
 	"golang.org/x/image/font/gofont/goregular"
 	"gorm.io/gorm"
 )
 
+// commandCooldown limits how often a user can run the same command
+const commandCooldown = 3 * time.Second
+
+func internalError(l discord.Locale) string {
+	return locale.Get(l).Error.Internal
+}
+
+func missingPermissions(l discord.Locale) string {
+	return locale.Get(l).Error.MissingPermissions
+}
+
 type Bot interface {
 	Start(ctx context.Context) error
 	Close(ctx context.Context)
@@ -36,6 +49,7 @@ type botImpl struct {
 	font   *truetype.Font
 
 	callManager    call.Manager
+	callHistory    call.History
 	formManager    form.Manager
 	ruleRepository rule.Repository
 	commandManager command.Manager
@@ -57,7 +71,7 @@ func New(token string, db *gorm.DB, opts ...ConfigOpt) (Bot, error) {
 
 	client, err := disgo.New(token,
 		bot.WithCacheConfigOpts(
-			cache.WithCaches(cache.FlagVoiceStates, cache.FlagMembers, cache.FlagGuilds, cache.FlagChannels),
+			cache.WithCaches(cache.FlagVoiceStates, cache.FlagMembers, cache.FlagGuilds, cache.FlagChannels, cache.FlagStageInstances),
 		),
 		bot.WithGatewayConfigOpts(
 			gateway.WithIntents(gateway.IntentGuilds, gateway.IntentGuildVoiceStates),
@@ -68,20 +82,48 @@ func New(token string, db *gorm.DB, opts ...ConfigOpt) (Bot, error) {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
+	// initialize rule manager
+	ruleRepository := rule.CreateRepository(db)
+
 	// initialize form manager
-	formManager := form.NewManager(client.Rest())
+	formManager := form.NewManager(client.Rest(),
+		form.WithStore(form.NewDBStore(db)),
+		form.WithExpiredMessage(func(l discord.Locale) string {
+			return locale.Get(l).Form.Expired
+		}),
+		form.WithRejectedMessage(func(l discord.Locale) string {
+			return locale.Get(l).Form.Rejected
+		}),
+	)
+	formManager.Register(iform.KindRule, iform.RuleFactory(ruleRepository))
+	formManager.Use(
+		middleware.Respond(internalError),
+		middleware.Recover(),
+		middleware.Log(),
+		// the forms change the settings of the guild
+		middleware.RequirePermissions(discord.PermissionManageGuild, missingPermissions),
+	)
 	client.AddEventListeners(bot.NewListenerFunc(formManager.OnComponentInteractionCreate))
+	client.AddEventListeners(bot.NewListenerFunc(formManager.OnModalSubmitInteractionCreate))
 
-	// initialize rule manager
-	ruleRepository := rule.CreateRepository(db)
+	// initialize call manager
+	callManager := call.NewManager(client.Rest())
+	callHistory := call.CreateHistory(db)
 
 	// initialize command for bot
 	commandManager := command.NewManager()
 	commandManager.Register(&icommand.Settings{Form: formManager, Rule: ruleRepository})
+	commandManager.Register(&icommand.Stats{Call: callManager, History: callHistory})
+	commandManager.Use(
+		middleware.Respond(internalError),
+		middleware.Recover(),
+		middleware.Log(),
+		middleware.Cooldown(commandCooldown, middleware.CommandName, func(l discord.Locale, remaining time.Duration) string {
+			return locale.Format(l, locale.Get(l).Error.Cooldown, locale.Args{"seconds": int(remaining.Seconds()) + 1})
+		}),
+	)
 	client.AddEventListeners(bot.NewListenerFunc(commandManager.OnCommandInteractionCreate))
-
-	// initialize call manager
-	callManager := call.NewManager(client.Rest())
+	client.AddEventListeners(bot.NewListenerFunc(commandManager.OnAutocompleteInteractionCreate))
 
 	font, err := truetype.Parse(goregular.TTF)
 
@@ -93,6 +135,7 @@ func New(token string, db *gorm.DB, opts ...ConfigOpt) (Bot, error) {
 		client:         client,
 		font:           font,
 		callManager:    callManager,
+		callHistory:    callHistory,
 		formManager:    formManager,
 		ruleRepository: ruleRepository,
 		commandManager: commandManager,
@@ -103,9 +146,15 @@ func New(token string, db *gorm.DB, opts ...ConfigOpt) (Bot, error) {
 		opt(b)
 	}
 
+	// the timelines are rendered again with the font given by the options
+	commandManager.Register(&icommand.History{History: callHistory, Font: b.font})
+
 	client.AddEventListeners(bot.NewListenerFunc(b.onVoiceStateUpdate))
 	client.AddEventListeners(bot.NewListenerFunc(b.onGuildsReady))
 	client.AddEventListeners(bot.NewListenerFunc(b.onGuildJoin))
+	client.AddEventListeners(bot.NewListenerFunc(b.onStageInstanceCreate))
+	client.AddEventListeners(bot.NewListenerFunc(b.onStageInstanceUpdate))
+	client.AddEventListeners(bot.NewListenerFunc(b.onStageInstanceDelete))
 
 	return b, nil
 }
@@ -115,6 +164,7 @@ func (b *botImpl) Start(ctx context.Context) error {
 }
 
 func (b *botImpl) Close(ctx context.Context) {
+	b.formManager.Close()
 	b.client.Close(ctx)
 }
 
@@ -191,6 +241,23 @@ func (b *botImpl) onVoiceStateUpdate(event *events.GuildVoiceStateUpdate) {
 		}
 	}
 
+	if event.VoiceState.SelfVideo != event.OldVoiceState.SelfVideo {
+		if event.VoiceState.SelfVideo {
+			handler.MemberStartVideo(event.Member.User.ID, now)
+		} else {
+			handler.MemberStopVideo(event.Member.User.ID, now)
+		}
+	}
+
+	// on stage channels, only the speakers are not suppressed
+	if event.VoiceState.Suppress != event.OldVoiceState.Suppress {
+		if event.VoiceState.Suppress {
+			handler.MemberBecomeAudience(event.Member.User.ID, now)
+		} else {
+			handler.MemberBecomeSpeaker(event.Member.User.ID, now)
+		}
+	}
+
 	handler.Update()
 
 }
@@ -216,7 +283,16 @@ func (b *botImpl) onJoinVoiceChannel(channelID snowflake.ID, member *discord.Mem
 			fmt.Println("rule is not enabled, skip")
 			return
 		}
-		handler, err = b.callManager.Add(call.New(discord.LocaleJapanese, rule, channel, b.font), now)
+		c := call.New(discord.LocaleJapanese, rule, channel, b.font)
+		c.Branding = b.branding(guildChannel.GuildID(), member)
+		if c.Stage {
+			instance, live := b.stageInstance(guildChannel.GuildID(), channelID)
+			if !live && rule.StageLiveOnly {
+				return
+			}
+			c.Topic = instance.Topic
+		}
+		handler, err = b.callManager.Add(c, now)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "failed to create call:", err)
 			return
@@ -238,11 +314,17 @@ func (b *botImpl) onJoinVoiceChannel(channelID snowflake.ID, member *discord.Mem
 	deaf := deaf(*afterVoiceState)
 	handler.MemberJoin(member.User.ID, now, mute, deaf)
 
-	// if the user is streaming, right after joining the voice channel,
-	// we need to mark the user as streaming
+	// if the user is streaming, using video or speaking on the stage right after joining the voice channel,
+	// we need to mark the user as such
 	if afterVoiceState.SelfStream {
 		handler.MemberStartStreaming(member.User.ID, now)
 	}
+	if afterVoiceState.SelfVideo {
+		handler.MemberStartVideo(member.User.ID, now)
+	}
+	if !afterVoiceState.Suppress {
+		handler.MemberBecomeSpeaker(member.User.ID, now)
+	}
 
 	handler.Update()
 	if cancel, ok := b.cancelClose[channelID]; ok {
@@ -251,6 +333,15 @@ func (b *botImpl) onJoinVoiceChannel(channelID snowflake.ID, member *discord.Mem
 	}
 }
 
+// branding resolves the branding of the call started by the member from the caches
+func (b *botImpl) branding(guildID snowflake.ID, member *discord.Member) call.Branding {
+	var guild *discord.Guild
+	if g, ok := b.client.Caches().Guild(guildID); ok {
+		guild = &g
+	}
+	return call.NewBranding(*member, b.client.Caches().MemberRoles(*member), guild)
+}
+
 func (b *botImpl) onLeaveVoiceChannel(channelID snowflake.ID, member *discord.Member, beforeVoiceState *discord.VoiceState) {
 	now := time.Now()
 	handler, ok := b.callManager.Get(channelID)
@@ -258,11 +349,17 @@ func (b *botImpl) onLeaveVoiceChannel(channelID snowflake.ID, member *discord.Me
 		return
 	}
 
-	// if the user is streaming, right before leaving the voice channel,
-	// we need to unmark the user as streaming
+	// if the user is streaming, using video or speaking on the stage right before leaving the voice channel,
+	// we need to unmark the user as such
 	if beforeVoiceState.SelfStream {
 		handler.MemberStopStreaming(member.User.ID, now)
 	}
+	if beforeVoiceState.SelfVideo {
+		handler.MemberStopVideo(member.User.ID, now)
+	}
+	if !beforeVoiceState.Suppress {
+		handler.MemberBecomeAudience(member.User.ID, now)
+	}
 	isEmpty := handler.MemberLeave(member.User.ID, now)
 
 	handler.Update()
@@ -282,8 +379,7 @@ func (b *botImpl) onLeaveVoiceChannel(channelID snowflake.ID, member *discord.Me
 			wait := 1 * time.Minute
 			select {
 			case <-time.After(wait):
-				handler.Close(now)
-				b.callManager.Remove(channelID)
+				b.closeCall(channelID, handler, now)
 			case <-cancel:
 			}
 
@@ -292,6 +388,89 @@ func (b *botImpl) onLeaveVoiceChannel(channelID snowflake.ID, member *discord.Me
 	}
 }
 
+// closeCall ends the call and stores it in the history,
+// unless the call is already closed by the other goroutine.
+func (b *botImpl) closeCall(channelID snowflake.ID, handler call.Handler, now time.Time) {
+	c := handler.Close(now)
+	if c == nil {
+		return
+	}
+	b.callManager.Remove(channelID)
+	if err := b.callHistory.Save(c); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to save call:", err)
+	}
+}
+
+func (b *botImpl) stageInstance(guildID snowflake.ID, channelID snowflake.ID) (discord.StageInstance, bool) {
+	var found *discord.StageInstance
+	b.client.Caches().StageInstanceForEach(guildID, func(stageInstance discord.StageInstance) {
+		if stageInstance.ChannelID == channelID {
+			found = &stageInstance
+		}
+	})
+	if found == nil {
+		return discord.StageInstance{}, false
+	}
+	return *found, true
+}
+
+func (b *botImpl) onStageInstanceCreate(event *events.StageInstanceCreate) {
+	channelID := event.StageInstance.ChannelID
+	if handler, ok := b.callManager.Get(channelID); ok {
+		handler.UpdateTopic(event.StageInstance.Topic)
+		handler.Update()
+		return
+	}
+
+	// the call might have been skipped because the stage was not live,
+	// so the members already on the stage join the call now
+	b.client.Caches().VoiceStatesForEach(event.StageInstance.GuildID, func(voiceState discord.VoiceState) {
+		if voiceState.ChannelID == nil || *voiceState.ChannelID != channelID {
+			return
+		}
+		member, ok := b.client.Caches().Member(event.StageInstance.GuildID, voiceState.UserID)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "failed to get member")
+			return
+		}
+		b.onJoinVoiceChannel(channelID, &member, &voiceState)
+	})
+}
+
+func (b *botImpl) onStageInstanceUpdate(event *events.StageInstanceUpdate) {
+	handler, ok := b.callManager.Get(event.StageInstance.ChannelID)
+	if !ok {
+		return
+	}
+	handler.UpdateTopic(event.StageInstance.Topic)
+	handler.Update()
+}
+
+func (b *botImpl) onStageInstanceDelete(event *events.StageInstanceDelete) {
+	channelID := event.StageInstance.ChannelID
+	handler, ok := b.callManager.Get(channelID)
+	if !ok {
+		return
+	}
+
+	// the live only call ends with the stage, and a new one starts when the stage is live again
+	if c := handler.Call(); c != nil && c.Rule.StageLiveOnly {
+		if cancel, ok := b.cancelClose[channelID]; ok {
+			// the call is closed here instead. if the shutdown sequence is already closing it, closeCall leaves it to that
+			select {
+			case cancel <- struct{}{}:
+			default:
+			}
+			delete(b.cancelClose, channelID)
+		}
+		b.closeCall(channelID, handler, time.Now())
+		return
+	}
+
+	handler.UpdateTopic("")
+	handler.Update()
+}
+
 func mute(voiceState discord.VoiceState) bool {
 	return voiceState.SelfMute || voiceState.GuildMute
 }
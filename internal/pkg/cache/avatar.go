@@ -1,10 +1,14 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"image"
-	"net/http"
+	"image/png"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
@@ -12,31 +16,148 @@ import (
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/makeitchaccha/ringring/pkg/extstd"
 	"github.com/nfnt/resize"
-	"golang.org/x/image/webp"
 )
 
-var avatarCache = make(map[snowflake.ID]extstd.Cache[image.Image])
+const (
+	DefaultAvatarCacheSize     = 512
+	DefaultAvatarCacheTTL      = 1 * time.Hour
+	DefaultAvatarCacheDiskSize = 4096
 
-func GetAvatar(rest rest.Rest, id snowflake.ID) (image.Image, error) {
-	if avatar, ok := avatarCache[id]; ok && avatar.Valid() {
-		return avatar.Unwrap(), nil
+	// avatarSweepInterval is how often the cache directory is swept while avatars are stored
+	avatarSweepInterval = 10 * time.Minute
+	// avatarTempExt is the extension of the avatars being written
+	avatarTempExt = ".tmp"
+)
+
+// AvatarRef identifies the effective avatar of a member.
+// GuildID is zero unless the member has set a guild specific avatar,
+// so the same user avatar is shared among the guilds.
+type AvatarRef struct {
+	GuildID snowflake.ID
+	UserID  snowflake.ID
+	Hash    string
+	// URL is empty if the avatar is not resolved yet
+	URL string
+}
+
+// MemberAvatar resolves the effective avatar of the member without any request.
+// the guild specific avatar is preferred over the user avatar.
+func MemberAvatar(member *discord.Member) AvatarRef {
+	opts := []discord.CDNOpt{discord.WithSize(64), discord.WithFormat(discord.FileFormatPNG)}
+
+	// guild avatar url cannot be built without the guild id
+	if member.Avatar != nil && member.GuildID != 0 {
+		return AvatarRef{
+			GuildID: member.GuildID,
+			UserID:  member.User.ID,
+			Hash:    *member.Avatar,
+			URL:     member.EffectiveAvatarURL(opts...),
+		}
 	}
 
-	return cacheAvatar(rest, id)
+	if member.User.ID == 0 {
+		return AvatarRef{}
+	}
+	return userAvatar(member.User)
 }
 
-func cacheAvatar(rest rest.Rest, id snowflake.ID) (image.Image, error) {
-	user, err := rest.GetUser(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+func userAvatar(user discord.User) AvatarRef {
+	ref := AvatarRef{
+		UserID: user.ID,
+		URL:    user.EffectiveAvatarURL(discord.WithSize(64), discord.WithFormat(discord.FileFormatPNG)),
 	}
-	resp, err := http.Get(user.EffectiveAvatarURL(discord.WithSize(64), discord.WithFormat(discord.FileFormatWebP)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get avatar: %w", err)
+	if user.Avatar != nil {
+		ref.Hash = *user.Avatar
 	}
-	avatar, err := webp.Decode(resp.Body)
+	return ref
+}
+
+// avatarKey contains the avatar hash,
+// so that a changed avatar is fetched again instead of hitting the cache.
+type avatarKey struct {
+	guildID snowflake.ID
+	userID  snowflake.ID
+	hash    string
+}
+
+// AvatarConfig configures the avatar cache
+type AvatarConfig struct {
+	// Size is the number of the avatars kept in memory
+	Size int
+	TTL  time.Duration
+	// Dir is empty when avatars are not persisted
+	Dir string
+	// DiskSize is the number of the avatars kept in Dir, the oldest ones are deleted first
+	DiskSize int
+}
+
+// the configuration is not synchronized, it is only replaced before the bot starts.
+var (
+	avatarSource   = NewHTTPAvatarSource(DefaultAvatarTimeout)
+	avatarCache    = extstd.NewLRU[avatarKey, image.Image](DefaultAvatarCacheSize, DefaultAvatarCacheTTL)
+	avatarCacheTTL = DefaultAvatarCacheTTL
+	// avatarCacheDir is empty when avatars are not persisted
+	avatarCacheDir      = ""
+	avatarCacheDiskSize = DefaultAvatarCacheDiskSize
+
+	// avatarSweep guards lastAvatarSweep, so that only one sweep runs at a time
+	avatarSweep     sync.Mutex
+	lastAvatarSweep time.Time
+)
+
+// ConfigureAvatar replaces the avatar cache, and sweeps the cache directory if any.
+// it must be called before the bot starts, since it is not safe to call concurrently with GetAvatar.
+func ConfigureAvatar(config AvatarConfig) error {
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create avatar cache directory: %w", err)
+		}
+	}
+
+	avatarCache = extstd.NewLRU[avatarKey, image.Image](config.Size, config.TTL)
+	avatarCacheTTL = config.TTL
+	avatarCacheDir = config.Dir
+	avatarCacheDiskSize = config.DiskSize
+
+	sweepAvatars(time.Now())
+	return nil
+}
+
+// SetAvatarSource replaces the source the avatars are fetched from.
+// same as ConfigureAvatar, it must be called before the bot starts.
+func SetAvatarSource(source AvatarSource) {
+	avatarSource = source
+}
+
+// GetAvatar returns the avatar of the ref.
+// the user is requested only if the ref is not resolved, i.e. the member was not available.
+func GetAvatar(ctx context.Context, client rest.Rest, ref AvatarRef) (image.Image, error) {
+	if ref.URL == "" {
+		user, err := client.GetUser(ref.UserID, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		ref = userAvatar(*user)
+	}
+
+	key := avatarKey{guildID: ref.GuildID, userID: ref.UserID, hash: ref.Hash}
+
+	if avatar, ok := avatarCache.Get(key); ok {
+		return avatar, nil
+	}
+
+	if avatar, ok := loadAvatar(key); ok {
+		avatarCache.Add(key, avatar)
+		return avatar, nil
+	}
+
+	return cacheAvatar(ctx, key, ref.URL)
+}
+
+func cacheAvatar(ctx context.Context, key avatarKey, url string) (image.Image, error) {
+	avatar, err := avatarSource.Fetch(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode avatar: %w", err)
+		return nil, err
 	}
 
 	if avatar.Bounds().Dx() != 64 || avatar.Bounds().Dy() != 64 {
@@ -45,6 +166,151 @@ func cacheAvatar(rest rest.Rest, id snowflake.ID) (image.Image, error) {
 		avatar = resize.Resize(64, 64, avatar, resize.Lanczos3)
 	}
 
-	avatarCache[id] = extstd.NewCache(avatar, 1*time.Hour)
+	avatarCache.Add(key, avatar)
+	storeAvatar(key, avatar)
 	return avatar, nil
 }
+
+func avatarPath(key avatarKey) string {
+	hash := key.hash
+	if hash == "" {
+		hash = "default"
+	}
+	if key.guildID != 0 {
+		return filepath.Join(avatarCacheDir, fmt.Sprintf("%s_%s_%s.png", key.guildID, key.userID, hash))
+	}
+	return filepath.Join(avatarCacheDir, fmt.Sprintf("%s_%s.png", key.userID, hash))
+}
+
+// loadAvatar loads the avatar from the cache directory, unless it is expired
+func loadAvatar(key avatarKey) (image.Image, bool) {
+	if avatarCacheDir == "" {
+		return nil, false
+	}
+
+	path := avatarPath(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if avatarCacheTTL > 0 && time.Since(info.ModTime()) > avatarCacheTTL {
+		os.Remove(path)
+		return nil, false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	avatar, err := png.Decode(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to decode cached avatar:", err)
+		return nil, false
+	}
+	return avatar, true
+}
+
+// storeAvatar stores the avatar into the cache directory.
+// failing to store is not fatal, the avatar is fetched again next time.
+func storeAvatar(key avatarKey, avatar image.Image) {
+	if avatarCacheDir == "" {
+		return
+	}
+
+	if err := writeAvatar(avatarPath(key), avatar); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to store avatar:", err)
+	}
+
+	// the files of the old avatar hashes are never loaded again, so they are swept from time to time
+	now := time.Now()
+	avatarSweep.Lock()
+	due := now.Sub(lastAvatarSweep) >= avatarSweepInterval
+	avatarSweep.Unlock()
+	if due {
+		go sweepAvatars(now)
+	}
+}
+
+// writeAvatar writes the avatar into a temporary file and renames it to the path,
+// so that the avatar written in parallel or failed halfway is never loaded.
+func writeAvatar(path string, avatar image.Image) error {
+	f, err := os.CreateTemp(avatarCacheDir, "avatar-*"+avatarTempExt)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(f, avatar)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// sweepAvatars deletes the expired avatars in the cache directory,
+// and the oldest ones beyond the disk size.
+func sweepAvatars(now time.Time) {
+	if avatarCacheDir == "" {
+		return
+	}
+
+	avatarSweep.Lock()
+	defer avatarSweep.Unlock()
+	lastAvatarSweep = now
+
+	entries, err := os.ReadDir(avatarCacheDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to sweep avatars:", err)
+		return
+	}
+
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	files := make([]file, 0, len(entries))
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".png" && ext != avatarTempExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(avatarCacheDir, entry.Name())
+		// the temporary files left by the crashes
+		if ext == avatarTempExt {
+			if now.Sub(info.ModTime()) > avatarSweepInterval {
+				os.Remove(path)
+			}
+			continue
+		}
+		if avatarCacheTTL > 0 && now.Sub(info.ModTime()) > avatarCacheTTL {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, file{path: path, modTime: info.ModTime()})
+	}
+
+	if avatarCacheDiskSize <= 0 || len(files) <= avatarCacheDiskSize {
+		return
+	}
+
+	// the latest ones are kept
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, f := range files[avatarCacheDiskSize:] {
+		os.Remove(f.path)
+	}
+}
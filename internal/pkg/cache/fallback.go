@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// fallbackColors are picked by the user id, so the same user always gets the same color
+var fallbackColors = []color.RGBA{
+	{R: 0x58, G: 0x65, B: 0xF2, A: 0xFF},
+	{R: 0x57, G: 0xF2, B: 0x87, A: 0xFF},
+	{R: 0xFE, G: 0xE7, B: 0x5C, A: 0xFF},
+	{R: 0xEB, G: 0x45, B: 0x9E, A: 0xFF},
+	{R: 0xED, G: 0x42, B: 0x45, A: 0xFF},
+	{R: 0x3B, G: 0xA5, B: 0x5D, A: 0xFF},
+	{R: 0xF0, G: 0x8C, B: 0x2E, A: 0xFF},
+}
+
+// FallbackAvatar generates an avatar with the initials of the name on a colored circle.
+// it is used when the actual avatar cannot be fetched.
+func FallbackAvatar(id snowflake.ID, name string, f *truetype.Font) image.Image {
+	const size = 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	c := fallbackColors[uint64(id)%uint64(len(fallbackColors))]
+
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+			if dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	if f == nil {
+		return img
+	}
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: truetype.NewFace(f, &truetype.Options{Size: 26}),
+	}
+	label := initials(name)
+	d.Dot = fixed.Point26_6{X: fixed.I(size/2) - d.MeasureString(label)/2, Y: fixed.I(size/2 + 9)}
+	d.DrawString(label)
+
+	return img
+}
+
+// initials returns the first letters of the first two words of the name
+func initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == '.' || r == '-'
+	})
+
+	letters := make([]rune, 0, 2)
+	for _, w := range words {
+		if len(letters) == 2 {
+			break
+		}
+		letters = append(letters, unicode.ToUpper([]rune(w)[0]))
+	}
+
+	if len(letters) == 0 {
+		return "?"
+	}
+	return string(letters)
+}
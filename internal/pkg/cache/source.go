@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"time"
+
+	// decoders used by image.Decode
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+const DefaultAvatarTimeout = 5 * time.Second
+
+// AvatarSource fetches the avatar image from the url.
+type AvatarSource interface {
+	Fetch(ctx context.Context, url string) (image.Image, error)
+}
+
+var _ AvatarSource = (*httpAvatarSource)(nil)
+
+type httpAvatarSource struct {
+	client *http.Client
+}
+
+// NewHTTPAvatarSource creates an AvatarSource which gives up fetching after timeout.
+// png, gif, jpeg and webp avatars are supported.
+func NewHTTPAvatarSource(timeout time.Duration) AvatarSource {
+	return &httpAvatarSource{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *httpAvatarSource) Fetch(ctx context.Context, url string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get avatar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get avatar: unexpected status %s", resp.Status)
+	}
+
+	avatar, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode avatar: %w", err)
+	}
+	return avatar, nil
+}
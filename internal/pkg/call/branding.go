@@ -0,0 +1,78 @@
+package call
+
+import (
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+	"github.com/makeitchaccha/ringring/internal/pkg/util"
+)
+
+// Branding is what the rule derives the appearance of the embeds from,
+// resolved when the call starts.
+type Branding struct {
+	// StarterColor is the color of the highest colored role of the starter, zero if none
+	StarterColor  int
+	StarterAvatar string
+	GuildIcon     string
+}
+
+// NewBranding resolves the branding from the member who started the call, with the roles of the member.
+// guild may be nil if it is not cached.
+func NewBranding(starter discord.Member, roles []discord.Role, guild *discord.Guild) Branding {
+	b := Branding{
+		StarterAvatar: starter.EffectiveAvatarURL(discord.WithSize(128)),
+	}
+
+	position := -1
+	for _, role := range roles {
+		// roles without color are transparent
+		if role.Color != 0 && role.Position > position {
+			b.StarterColor, position = role.Color, role.Position
+		}
+	}
+
+	if guild != nil {
+		if icon := guild.IconURL(discord.WithSize(128)); icon != nil {
+			b.GuildIcon = *icon
+		}
+	}
+	return b
+}
+
+// color returns the color of the embeds in the state
+func (c *Call) color(state string) int {
+	switch c.Rule.ColorSource {
+	case rule.ColorSourceChannel:
+		// the ended calls are shown with the muted color of the channel
+		if state == rule.TemplateStateEnded {
+			return util.ColorToInt(util.HashColor(uint64(c.ChannelID), 0.15, 0.5))
+		}
+		return util.ColorToInt(util.HashColor(uint64(c.ChannelID), 0.45, 0.6))
+	case rule.ColorSourceStarterRole:
+		if c.Branding.StarterColor != 0 {
+			return c.Branding.StarterColor
+		}
+	}
+
+	color := c.Rule.OngoingColor
+	if state == rule.TemplateStateEnded {
+		color = c.Rule.EndedColor
+	}
+	if color == nil {
+		return rule.DefaultColor
+	}
+	return *color
+}
+
+// thumbnail returns the url of the thumbnail, empty if none
+func (c *Call) thumbnail() string {
+	switch c.Rule.Thumbnail {
+	case rule.ThumbnailGuildIcon:
+		return c.Branding.GuildIcon
+	case rule.ThumbnailStarterAvatar:
+		return c.Branding.StarterAvatar
+	case rule.ThumbnailCustom:
+		return c.Rule.ThumbnailURL
+	default:
+		return ""
+	}
+}
@@ -1,46 +1,64 @@
 package call
 
 import (
-	"fmt"
-	"image/color"
 	"strings"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
-	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/golang/freetype/truetype"
-	"github.com/makeitchaccha/design/timeline"
-	"github.com/makeitchaccha/ringring/internal/pkg/cache"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"github.com/makeitchaccha/ringring/internal/pkg/rule"
-	"github.com/makeitchaccha/ringring/internal/pkg/util"
 )
 
 type Call struct {
 	Locale      discord.Locale
 	Font        *truetype.Font
 	Rule        rule.Rule
+	GuildID     snowflake.ID
 	ChannelID   snowflake.ID
 	ChannelName string
-	Start       time.Time
-	End         time.Time
-	Members     []*Member
-	MemberMap   map[snowflake.ID]*Member
-	Onlines     int
+	// ChannelLabel is the plain name of the channel, ChannelName may be a mention
+	ChannelLabel string
+	Branding     Branding
+	Stage        bool
+	Topic        string
+	Start        time.Time
+	End          time.Time
+	Members      []*Member
+	MemberMap    map[snowflake.ID]*Member
+	Onlines      int
+}
+
+// clone copies the call with the members, so that the copy can be read while the call goes on
+func (c *Call) clone() *Call {
+	cp := *c
+	cp.Members = make([]*Member, len(c.Members))
+	cp.MemberMap = make(map[snowflake.ID]*Member, len(c.Members))
+	for i, m := range c.Members {
+		cp.Members[i] = m.clone()
+		cp.MemberMap[m.id] = cp.Members[i]
+	}
+	return &cp
 }
 
 func New(locale discord.Locale, rule rule.Rule, channel discord.Channel, font *truetype.Font) *Call {
-	return &Call{
-		Locale:      locale,
-		Font:        font,
-		Rule:        rule,
-		ChannelID:   channel.ID(),
-		ChannelName: rule.ChannelFormat.Format(channel),
-		Members:     make([]*Member, 0),
-		MemberMap:   make(map[snowflake.ID]*Member),
-		Onlines:     0,
+	c := &Call{
+		Locale:       locale,
+		Font:         font,
+		Rule:         rule,
+		ChannelID:    channel.ID(),
+		ChannelName:  rule.ChannelFormat.Format(channel),
+		ChannelLabel: channel.Name(),
+		Stage:        channel.Type() == discord.ChannelTypeGuildStageVoice,
+		Members:      make([]*Member, 0),
+		MemberMap:    make(map[snowflake.ID]*Member),
+		Onlines:      0,
 	}
+	if guildChannel, ok := channel.(discord.GuildChannel); ok {
+		c.GuildID = guildChannel.GuildID()
+	}
+	return c
 }
 
 func (c *Call) OnStart(now time.Time) {
@@ -51,6 +69,11 @@ func (c *Call) OnEnd(now time.Time) {
 	c.End = now
 }
 
+// ended reports whether the call is ended, e.g. loaded from the history
+func (c *Call) ended() bool {
+	return !c.End.IsZero()
+}
+
 func (c *Call) elapsed(now time.Time) time.Duration {
 	return now.Sub(c.Start)
 }
@@ -59,20 +82,29 @@ func (c *Call) OngoingEmbed(now time.Time) discord.Embed {
 	n := locale.Get(c.Locale).Notification
 	builder := discord.NewEmbedBuilder().
 		SetTitle(n.Ongoing.Title).
-		SetDescriptionf(n.Ongoing.Description, c.ChannelName).
-		SetColor(0x547443).
+		SetDescription(locale.Format(c.Locale, n.Ongoing.Description, locale.Args{"channel": c.ChannelName})).
+		SetColor(c.color(rule.TemplateStateOngoing)).
 		AddField(n.Common.StartTime, discord.FormattedTimestampMention(c.Start.Unix(), discord.TimestampStyleShortTime), true).
 		AddField(n.Common.TimeElapsed, localizeDuration(c.Locale, c.elapsed(now), false), true)
 
+	if c.Stage && c.Topic != "" {
+		builder.AddField(n.Stage.Topic, c.Topic, false)
+	}
+
 	if c.Rule.History.ShouldDisplayName() {
-		builder.AddField(n.Common.History, c.history(now), false)
+		c.addHistoryFields(builder, now)
+	}
+
+	if c.shouldEmbedTimeline() {
+		builder.SetImage("attachment://" + c.Rule.TimelineFormat.Filename())
 	}
 
-	// testing ...
-	if c.Rule.History.ShouldDisplayTimeline() {
-		builder.SetImage("attachment://thumbnail.png")
+	if thumbnail := c.thumbnail(); thumbnail != "" {
+		builder.SetThumbnail(thumbnail)
 	}
 
+	c.applyTemplate(builder, rule.TemplateStateOngoing, now)
+
 	return builder.Build()
 }
 
@@ -80,109 +112,122 @@ func (c *Call) EndedEmbed() discord.Embed {
 	n := locale.Get(c.Locale).Notification
 	builder := discord.NewEmbedBuilder().
 		SetTitle(n.Ended.Title).
-		SetDescriptionf(n.Ended.Description, c.ChannelName).
-		SetColor(0x547443).
+		SetDescription(locale.Format(c.Locale, n.Ended.Description, locale.Args{"channel": c.ChannelName})).
+		SetColor(c.color(rule.TemplateStateEnded)).
 		AddField(n.Common.StartTime, discord.FormattedTimestampMention(c.Start.Unix(), discord.TimestampStyleShortTime), true).
 		AddField(n.Common.EndTime, discord.FormattedTimestampMention(c.End.Unix(), discord.TimestampStyleShortTime), true).
 		AddField(n.Common.TimeElapsed, localizeDuration(c.Locale, c.elapsed(c.End), false), true)
 
+	if c.Stage && c.Topic != "" {
+		builder.AddField(n.Stage.Topic, c.Topic, false)
+	}
+
 	if c.Rule.History.ShouldDisplayName() {
-		builder.AddField(n.Common.History, c.history(c.End), false)
+		c.addHistoryFields(builder, c.End)
+	}
+
+	if c.shouldEmbedTimeline() {
+		builder.SetImage("attachment://" + c.Rule.TimelineFormat.Filename())
 	}
 
-	if c.Rule.History.ShouldDisplayTimeline() {
-		builder.SetImage("attachment://thumbnail.png")
+	if thumbnail := c.thumbnail(); thumbnail != "" {
+		builder.SetThumbnail(thumbnail)
 	}
 
+	c.applyTemplate(builder, rule.TemplateStateEnded, c.End)
+
 	return builder.Build()
 }
 
-type GenerateOptions func(b *timeline.TimelineBuilder)
+// StatsEmbed shows how the member participated in the call so far, or in the whole call if it is ended.
+func (c *Call) StatsEmbed(l discord.Locale, member *Member, now time.Time) discord.Embed {
+	n := locale.Get(l).Notification
+	state, description := rule.TemplateStateOngoing, n.Ongoing.Description
+	if c.ended() {
+		state, description, now = rule.TemplateStateEnded, n.Ended.Description, c.End
+	}
+
+	stats := member.Stats(now)
+	builder := discord.NewEmbedBuilder().
+		SetTitle(locale.Format(l, n.Stats.Title, locale.Args{"member": member.label})).
+		SetDescription(locale.Format(l, description, locale.Args{"channel": c.ChannelName})).
+		SetColor(c.color(state)).
+		AddField(n.Common.StartTime, discord.FormattedTimestampMention(c.Start.Unix(), discord.TimestampStyleShortTime), true).
+		AddField(n.Stats.Online, localizeDuration(l, stats.Online, true), true)
+
+	if member.HasStreamed() {
+		builder.AddField(n.Stats.Streaming, localizeDuration(l, stats.Streaming, true), true)
+	}
+	if member.HasUsedVideo() {
+		builder.AddField(n.Stats.Video, localizeDuration(l, stats.Video, true), true)
+	}
+	if c.Stage {
+		builder.AddField(n.Stats.Speaker, localizeDuration(l, stats.Speaker, true), true)
+	}
+
+	return builder.Build()
+}
 
-func WithIndicator(indicator time.Time) GenerateOptions {
-	return func(b *timeline.TimelineBuilder) {
-		b.SetIndicator(indicator)
+// Summary is the plain text describing the call, e.g. to pick it from the choices.
+func (c *Call) Summary(l discord.Locale, now time.Time) string {
+	if c.ended() {
+		return c.Entry().Summary(l)
 	}
+	return truncate(locale.Format(l, locale.Get(l).Notification.Stats.Summary, locale.Args{
+		"start":   formatStart(l, c.Start),
+		"channel": c.ChannelLabel,
+		"elapsed": localizeDuration(l, c.elapsed(now), false),
+	}), maxChoiceName)
 }
 
-func (c *Call) GenerateTimeline(rest rest.Rest, now time.Time, frame time.Time, opts ...GenerateOptions) (*discord.File, error) {
+func (c *Call) shouldEmbedTimeline() bool {
+	return c.Rule.History.ShouldDisplayTimeline() && c.Rule.TimelineFormat.IsEmbeddable()
+}
 
-	// generate timeline
-	builder := timeline.NewTimelineBuilder(c.Start, frame)
+// addHistoryFields adds the history of the members.
+// on stage channels, the speakers are listed separately from the audience.
+func (c *Call) addHistoryFields(builder *discord.EmbedBuilder, now time.Time) {
+	n := locale.Get(c.Locale).Notification
 
-	for _, opt := range opts {
-		opt(builder)
+	if !c.Stage {
+		builder.AddField(n.Common.History, c.history(c.Members, now), false)
+		return
 	}
 
+	speakers := make([]*Member, 0)
+	audience := make([]*Member, 0)
 	for _, m := range c.Members {
-		avatar, err := cache.GetAvatar(rest, m.id)
-		if err != nil {
-			return nil, err
-		}
-		e := timeline.NewEntryBuilder(avatar)
-		c := util.TransformColorWithSpecificLuminance(util.ExtractMainColor(avatar), 0.7)
-		onlineSeries := timeline.NewSeriesBuilder(2.0/7.0, c)
-		for _, log := range m.onlineSections {
-			if log.end.IsZero() {
-				log.end = now
-			}
-			alpha := 1.0
-			if log.mute {
-				alpha = 0.8
-			}
-			if log.deaf {
-				alpha = 0.6
-			}
-			onlineSeries.AddSection(log.start, log.end, timeline.WithAlpha(alpha))
-		}
-		e.AddSeries(onlineSeries.Build())
-		if m.HasStreamed() {
-			streamingSeries := timeline.NewSeriesBuilder(0.5/7.0, color.RGBA64{R: 0xB000, G: 0x0000, B: 0x0000, A: 0xFFFF})
-			for _, log := range m.streamingSections {
-				if log.end.IsZero() {
-					log.end = now
-				}
-				streamingSeries.AddSection(log.start, log.end)
-			}
-			e.AddSeries(streamingSeries.Build())
+		if m.HasSpoken() {
+			speakers = append(speakers, m)
+		} else {
+			audience = append(audience, m)
 		}
-		builder.AddEntries(e.Build())
 	}
 
-	// prevent generated timeline from being too long in the vertical direction
-	// w:h = 4:3 is used in the thumbnail
-	layout := timeline.DefaultLayout()
-	if h, w := builder.Height(), builder.Width(); 4*h > 3*w {
-		// adjust width to fit the timeline
-		dw := 4*h/3 - w
-		layout.TimelineWidth += dw
+	if len(speakers) > 0 {
+		builder.AddField(n.Stage.Speakers, c.history(speakers, now), false)
+	}
+	if len(audience) > 0 {
+		builder.AddField(n.Stage.Audience, c.history(audience, now), false)
 	}
-
-	builder.SetLayout(layout)
-
-	// font modification
-	ticsFont := truetype.NewFace(c.Font, &truetype.Options{
-		Size: 15,
-	})
-
-	builder.MainTics.Label.Font = ticsFont
-	builder.SubTics.Label.Font = ticsFont
-
-	r := builder.Build().Generate()
-
-	return &discord.File{
-		Name:   "thumbnail.png",
-		Reader: r,
-	}, nil
 }
 
-func (c *Call) history(now time.Time) string {
+func (c *Call) history(members []*Member, now time.Time) string {
 	var sb strings.Builder
-	for _, m := range c.Members {
+	for _, m := range members {
 		sb.WriteString(m.name)
 		if c.Rule.History.ShouldDisplayDuration() {
+			stats := m.Stats(now)
 			sb.WriteString(" (")
-			sb.WriteString(localizeDuration(c.Locale, m.calculateDuration(now), true))
+			sb.WriteString(localizeDuration(c.Locale, stats.Online, true))
+			if m.HasSpoken() {
+				sb.WriteString(" 🎙️ ")
+				sb.WriteString(localizeDuration(c.Locale, stats.Speaker, true))
+			}
+			if m.HasUsedVideo() {
+				sb.WriteString(" 📹 ")
+				sb.WriteString(localizeDuration(c.Locale, stats.Video, true))
+			}
 			sb.WriteString(")")
 		}
 		sb.WriteString("\n")
@@ -198,19 +243,19 @@ func localizeDuration(l discord.Locale, d time.Duration, withSecond bool) string
 
 	t := locale.Get(l).Notification.Common.Timeformat
 
-	var sb strings.Builder
+	parts := make([]string, 0, 4)
 	if days > 0 {
-		sb.WriteString(fmt.Sprintf("%d%s", days, t.Days))
+		parts = append(parts, locale.Format(l, t.Days, locale.Args{"count": days}))
 	}
 	if days > 0 || hours > 0 {
-		sb.WriteString(fmt.Sprintf("%d%s", hours, t.Hours))
+		parts = append(parts, locale.Format(l, t.Hours, locale.Args{"count": hours}))
 	}
 	if days > 0 || hours > 0 || minutes > 0 || !withSecond {
-		sb.WriteString(fmt.Sprintf("%d%s", minutes, t.Minutes))
+		parts = append(parts, locale.Format(l, t.Minutes, locale.Args{"count": minutes}))
 	}
 	if withSecond {
-		sb.WriteString(fmt.Sprintf("%d%s", seconds, t.Seconds))
+		parts = append(parts, locale.Format(l, t.Seconds, locale.Args{"count": seconds}))
 	}
 
-	return sb.String()
+	return strings.Join(parts, t.Separator)
 }
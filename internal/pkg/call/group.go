@@ -0,0 +1,176 @@
+package call
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/golang/freetype/truetype"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// avatarWorkers limits the number of avatars fetched at the same time
+const avatarWorkers = 4
+
+// splitMembers returns the members drawn in their own entry, and the others aggregated into a single entry.
+// the members with the longest duration are kept in the order they joined.
+func (c *Call) splitMembers(now time.Time) (members []*Member, others []*Member) {
+	limit := c.Rule.TimelineMaxMembers
+	// it makes no sense to aggregate only one member
+	if limit <= 0 || len(c.Members) <= limit+1 {
+		return c.Members, nil
+	}
+
+	ranked := make([]*Member, len(c.Members))
+	copy(ranked, c.Members)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].calculateDuration(now) > ranked[j].calculateDuration(now)
+	})
+
+	top := make(map[*Member]bool, limit)
+	for _, m := range ranked[:limit] {
+		top[m] = true
+	}
+
+	for _, m := range c.Members {
+		if top[m] {
+			members = append(members, m)
+		} else {
+			others = append(others, m)
+		}
+	}
+	return members, others
+}
+
+// fetchAvatars fetches the avatars of the members in parallel with a bounded number of workers.
+// an avatar which cannot be fetched is replaced with a generated one, so it never blocks the timeline.
+func (c *Call) fetchAvatars(rest rest.Rest, members []*Member) []image.Image {
+	avatars := make([]image.Image, len(members))
+
+	sem := make(chan struct{}, avatarWorkers)
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, m *Member) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), cache.DefaultAvatarTimeout)
+			defer cancel()
+
+			avatar, err := cache.GetAvatar(ctx, rest, m.avatar)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "failed to get avatar, using fallback:", err)
+				avatar = cache.FallbackAvatar(m.id, m.label, c.Font)
+			}
+			avatars[i] = avatar
+		}(i, m)
+	}
+	wg.Wait()
+
+	return avatars
+}
+
+// othersEntry aggregates the members into a single entry.
+// the more members are online at the same time, the more opaque the section is.
+func (c *Call) othersEntry(others []*Member, now time.Time, th theme) timelineEntry {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+
+	events := make([]event, 0)
+	for _, m := range others {
+		for _, log := range m.onlineSections {
+			end := log.end
+			if end.IsZero() {
+				end = now
+			}
+			events = append(events, event{at: log.start, delta: 1}, event{at: end, delta: -1})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	series := timelineSeries{
+		height: 2.0 / 7.0,
+		color:  th.grid,
+	}
+	onlines := 0
+	for i, e := range events {
+		onlines += e.delta
+		if onlines <= 0 || i+1 >= len(events) || !events[i+1].at.After(e.at) {
+			continue
+		}
+		series.sections = append(series.sections, timelineSection{
+			start: e.at,
+			end:   events[i+1].at,
+			alpha: 0.3 + 0.7*float64(onlines)/float64(len(others)),
+		})
+	}
+
+	return timelineEntry{
+		label:  locale.Format(c.Locale, locale.Get(c.Locale).Notification.Timeline.Others, locale.Args{"count": len(others)}),
+		avatar: c.othersAvatar(len(others), th),
+		series: []timelineSeries{series},
+	}
+}
+
+// othersAvatar draws a circle with the number of the aggregated members, instead of an avatar.
+func (c *Call) othersAvatar(count int, th theme) image.Image {
+	const size = 64
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+			if dx*dx+dy*dy <= r*r {
+				img.Set(x, y, th.grid)
+			}
+		}
+	}
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: truetype.NewFace(c.Font, &truetype.Options{Size: 22}),
+	}
+	label := fmt.Sprintf("+%d", count)
+	d.Dot = fixed.Point26_6{X: fixed.I(size/2) - d.MeasureString(label)/2, Y: fixed.I(size/2 + 8)}
+	d.DrawString(label)
+
+	return img
+}
+
+// fitImage scales the image down so that neither side exceeds limit.
+// limit of zero or less means no limit.
+func fitImage(img image.Image, limit int) image.Image {
+	b := img.Bounds()
+	if limit <= 0 || (b.Dx() <= limit && b.Dy() <= limit) {
+		return img
+	}
+
+	scale := float64(limit) / float64(b.Dx())
+	if b.Dy() > b.Dx() {
+		scale = float64(limit) / float64(b.Dy())
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, int(float64(b.Dx())*scale), int(float64(b.Dy())*scale)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
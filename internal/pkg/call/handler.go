@@ -2,12 +2,14 @@ package call
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/makeitchaccha/design/timeline"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
 )
 
 // handler helps to update the call status
@@ -19,14 +21,32 @@ type Handler interface {
 	MemberLeave(userID snowflake.ID, now time.Time) (isEmpty bool)
 	MemberStartStreaming(userID snowflake.ID, time time.Time)
 	MemberStopStreaming(userID snowflake.ID, time time.Time)
+	MemberStartVideo(userID snowflake.ID, time time.Time)
+	MemberStopVideo(userID snowflake.ID, time time.Time)
+	// MemberBecomeSpeaker and MemberBecomeAudience are ignored unless the call is on a stage channel
+	MemberBecomeSpeaker(userID snowflake.ID, time time.Time)
+	MemberBecomeAudience(userID snowflake.ID, time time.Time)
+	// UpdateTopic updates the topic of the stage instance, empty if the stage is not live
+	UpdateTopic(topic string)
 
 	Update() error
-	Close(t time.Time) error
+	// Close ends the call, and returns the copy of the ended call.
+	// it returns nil if the handler is already closed, e.g. by the other goroutine.
+	Close(t time.Time) *Call
 
 	IsClosed() bool
+	// Call returns the call handled, only the fields fixed at the start can be read.
+	// it returns nil once the handler is closed and the ended message is updated.
+	Call() *Call
+	// Snapshot returns the copy of the ongoing call, which can be read while the call goes on.
+	// it returns nil once the handler is closed.
+	Snapshot() *Call
 }
 
 type handlerImpl struct {
+	// mu guards the call with the members, closed and updateCooldown,
+	// since the commands and the update goroutine read them while the events update them.
+	mu             sync.RWMutex
 	call           *Call
 	rest           rest.Rest
 	channelID      snowflake.ID
@@ -58,21 +78,36 @@ func NewHandler(call *Call, rest rest.Rest, now time.Time) (Handler, error) {
 }
 
 func (h *handlerImpl) RegisterMember(userID snowflake.ID, member *discord.Member) {
-	if h.IsRegistered(userID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.call.MemberMap[userID]; ok {
 		panic("member already registered")
 	}
 
-	m := NewMember(userID, h.call.Rule.UserFormat.Format(member))
+	avatar := cache.MemberAvatar(member)
+	if avatar.UserID == 0 {
+		// resolved later by requesting the user
+		avatar.UserID = userID
+	}
+
+	m := NewMember(userID, h.call.Rule.UserFormat.Format(member), member.EffectiveName(), avatar)
 	h.call.Members = append(h.call.Members, m)
 	h.call.MemberMap[userID] = m
 }
 
 func (h *handlerImpl) IsRegistered(userID snowflake.ID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	_, ok := h.call.MemberMap[userID]
 	return ok
 }
 
 func (h *handlerImpl) MemberJoin(userID snowflake.ID, now time.Time, mute, deaf bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	member, ok := h.call.MemberMap[userID]
 	if !ok {
 		panic("member not registered")
@@ -83,6 +118,9 @@ func (h *handlerImpl) MemberJoin(userID snowflake.ID, now time.Time, mute, deaf
 }
 
 func (h *handlerImpl) MemberUpdate(userID snowflake.ID, now time.Time, mute, deaf bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	member, ok := h.call.MemberMap[userID]
 	if !ok {
 		panic("member not registered")
@@ -92,6 +130,9 @@ func (h *handlerImpl) MemberUpdate(userID snowflake.ID, now time.Time, mute, dea
 }
 
 func (h *handlerImpl) MemberLeave(userID snowflake.ID, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	member, ok := h.call.MemberMap[userID]
 	if !ok {
 		panic("member not registered")
@@ -104,6 +145,9 @@ func (h *handlerImpl) MemberLeave(userID snowflake.ID, now time.Time) bool {
 }
 
 func (h *handlerImpl) MemberStartStreaming(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	member, ok := h.call.MemberMap[userID]
 	if !ok {
 		panic("member not registered")
@@ -112,6 +156,9 @@ func (h *handlerImpl) MemberStartStreaming(userID snowflake.ID, now time.Time) {
 }
 
 func (h *handlerImpl) MemberStopStreaming(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	member, ok := h.call.MemberMap[userID]
 	if !ok {
 		panic("member not registered")
@@ -119,23 +166,84 @@ func (h *handlerImpl) MemberStopStreaming(userID snowflake.ID, now time.Time) {
 	member.UnmarkAsStreaming(now)
 }
 
-func (h *handlerImpl) Update() error {
-	if h.closed {
-		return nil
+func (h *handlerImpl) MemberStartVideo(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	member, ok := h.call.MemberMap[userID]
+	if !ok {
+		panic("member not registered")
+	}
+	member.MarkAsVideo(now)
+}
+
+func (h *handlerImpl) MemberStopVideo(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	member, ok := h.call.MemberMap[userID]
+	if !ok {
+		panic("member not registered")
+	}
+	member.UnmarkAsVideo(now)
+}
+
+func (h *handlerImpl) MemberBecomeSpeaker(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.call.Stage {
+		return
+	}
+	member, ok := h.call.MemberMap[userID]
+	if !ok {
+		panic("member not registered")
 	}
+	member.MarkAsSpeaker(now)
+}
+
+func (h *handlerImpl) MemberBecomeAudience(userID snowflake.ID, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
+	if !h.call.Stage {
+		return
+	}
+	member, ok := h.call.MemberMap[userID]
+	if !ok {
+		panic("member not registered")
+	}
+	member.UnmarkAsSpeaker(now)
+}
+
+func (h *handlerImpl) UpdateTopic(topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.call.Topic = topic
+}
+
+func (h *handlerImpl) Update() error {
 	now := time.Now()
-	if h.updateCooldown.After(now) {
-		// update too fast
+
+	h.mu.Lock()
+	if h.closed || h.updateCooldown.After(now) {
+		// closed or update too fast
+		h.mu.Unlock()
 		return nil
 	}
+	// reserved until the update is done, so that the events do not update it at the same time
+	h.updateCooldown = now.Add(10 * time.Second)
+	// the timeline is rendered from the copy, so that the events are not blocked by the rendering
+	call := h.call.clone()
+	h.mu.Unlock()
 
 	messageUpdate := discord.NewMessageUpdateBuilder().
-		AddEmbeds(h.call.OngoingEmbed(now))
+		AddEmbeds(call.OngoingEmbed(now))
 
-	if h.call.Rule.History.ShouldDisplayTimeline() {
-		frame := timeline.GenFrame(h.call.Start, now)
-		file, err := h.call.GenerateTimeline(h.rest, now, frame, WithIndicator(now))
+	if call.Rule.History.ShouldDisplayTimeline() {
+		frame := timeline.GenFrame(call.Start, now)
+		file, err := call.GenerateTimeline(h.rest, now, frame, WithIndicator(now))
 		if err != nil {
 			fmt.Println("failed to generate timeline:", err)
 			return err
@@ -148,30 +256,45 @@ func (h *handlerImpl) Update() error {
 		h.messageID,
 		messageUpdate.Build(),
 	)
+
+	h.mu.Lock()
 	h.updateCooldown = time.Now().Add(10 * time.Second)
+	h.mu.Unlock()
 	return err
 }
 
-func (h *handlerImpl) Close(currentTime time.Time) error {
+func (h *handlerImpl) Close(currentTime time.Time) *Call {
+	h.mu.Lock()
 	if h.closed {
+		h.mu.Unlock()
 		return nil
 	}
+	h.closed = true
 
 	h.call.OnEnd(currentTime)
 
+	// the members still in the channel leave the call, e.g. when the stage ends
+	for _, m := range h.call.Members {
+		m.leave(currentTime)
+	}
+	call := h.call.clone()
+	h.mu.Unlock()
+
 	// update the message to show the call has ended
 	// this is IMPORTANT MESSAGE, so we should retry if failed
 	go func(currentTime time.Time) {
 		defer func() {
+			h.mu.Lock()
 			h.call = nil
+			h.mu.Unlock()
 		}()
 		retryInterval := 10 * time.Second
 		for retry := 0; retry < 3; retry++ {
 			messageUpdate := discord.NewMessageUpdateBuilder().
-				AddEmbeds(h.call.EndedEmbed())
+				AddEmbeds(call.EndedEmbed())
 
-			if h.call.Rule.History.ShouldDisplayTimeline() {
-				file, err := h.call.GenerateTimeline(h.rest, currentTime, currentTime)
+			if call.Rule.History.ShouldDisplayTimeline() {
+				file, err := call.GenerateTimeline(h.rest, currentTime, currentTime)
 				if err != nil {
 					fmt.Println("failed to generate timeline:", err)
 					return
@@ -195,11 +318,26 @@ func (h *handlerImpl) Close(currentTime time.Time) error {
 		}
 	}(currentTime)
 
-	h.closed = true
-
-	return nil
+	return call
 }
 
 func (h *handlerImpl) IsClosed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	return h.closed
 }
+
+func (h *handlerImpl) Call() *Call {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.call
+}
+
+func (h *handlerImpl) Snapshot() *Call {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return nil
+	}
+	return h.call.clone()
+}
@@ -0,0 +1,317 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/golang/freetype/truetype"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+	"gorm.io/gorm"
+)
+
+var ErrNotInHistory = errors.New("call is not in the history")
+
+// maxHistory is the number of the ended calls kept for each guild, the older ones are deleted
+const maxHistory = 100
+
+// maxChoiceName is the length of the autocomplete choices discord accepts
+const maxChoiceName = 100
+
+// Entry is the summary of a call in the history
+type Entry struct {
+	GuildID      snowflake.ID
+	ChannelID    snowflake.ID
+	ChannelLabel string
+	Start        time.Time
+	End          time.Time
+}
+
+// Summary is the plain text describing the call, e.g. to pick it from the choices.
+func (e Entry) Summary(l discord.Locale) string {
+	return truncate(locale.Format(l, locale.Get(l).Notification.Stats.EndedSummary, locale.Args{
+		"start":    formatStart(l, e.Start),
+		"channel":  e.ChannelLabel,
+		"duration": localizeDuration(l, e.End.Sub(e.Start), false),
+	}), maxChoiceName)
+}
+
+// Entry returns the summary of the call
+func (c *Call) Entry() Entry {
+	return Entry{
+		GuildID:      c.GuildID,
+		ChannelID:    c.ChannelID,
+		ChannelLabel: c.ChannelLabel,
+		Start:        c.Start,
+		End:          c.End,
+	}
+}
+
+// History stores the ended calls by the guild and the start time,
+// so that they can be looked up after they end.
+type History interface {
+	Save(c *Call) error
+	// Entries returns the calls of the guild, from the latest one
+	Entries(guildID snowflake.ID) ([]Entry, error)
+	// Load returns ErrNotInHistory if no call of the guild started at the time.
+	// the font of the call is set to render the timeline again.
+	Load(guildID snowflake.ID, start time.Time, font *truetype.Font) (*Call, error)
+}
+
+type CallModel struct {
+	GuildID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	// StartedAt is in unix milliseconds, since some databases drop the fraction of the time
+	StartedAt    int64 `gorm:"primaryKey;autoIncrement:false"`
+	ChannelID    uint64
+	ChannelLabel string
+	EndedAt      int64
+	Data         []byte
+}
+
+func (m CallModel) toEntry() Entry {
+	return Entry{
+		GuildID:      snowflake.ID(m.GuildID),
+		ChannelID:    snowflake.ID(m.ChannelID),
+		ChannelLabel: m.ChannelLabel,
+		Start:        time.UnixMilli(m.StartedAt),
+		End:          time.UnixMilli(m.EndedAt),
+	}
+}
+
+var _ History = (*historyImpl)(nil)
+
+type historyImpl struct {
+	db *gorm.DB
+}
+
+func CreateHistory(db *gorm.DB) History {
+	db.AutoMigrate(&CallModel{})
+
+	return &historyImpl{
+		db: db,
+	}
+}
+
+func (h *historyImpl) Save(c *Call) error {
+	data, err := json.Marshal(newCallRecord(c))
+	if err != nil {
+		return fmt.Errorf("failed to marshal call: %w", err)
+	}
+
+	model := CallModel{
+		GuildID:      uint64(c.GuildID),
+		StartedAt:    c.Start.UnixMilli(),
+		ChannelID:    uint64(c.ChannelID),
+		ChannelLabel: c.ChannelLabel,
+		EndedAt:      c.End.UnixMilli(),
+		Data:         data,
+	}
+	if err := h.db.Save(&model).Error; err != nil {
+		return fmt.Errorf("failed to save call: %w", err)
+	}
+
+	// the calls older than the latest ones are deleted
+	var oldest []CallModel
+	err = h.db.Omit("data").
+		Where("guild_id = ?", model.GuildID).
+		Order("started_at DESC").
+		Offset(maxHistory - 1).
+		Limit(1).
+		Find(&oldest).Error
+	if err != nil {
+		return fmt.Errorf("failed to find the oldest call: %w", err)
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+	if err := h.db.Delete(&CallModel{}, "guild_id = ? AND started_at < ?", model.GuildID, oldest[0].StartedAt).Error; err != nil {
+		return fmt.Errorf("failed to delete old calls: %w", err)
+	}
+	return nil
+}
+
+func (h *historyImpl) Entries(guildID snowflake.ID) ([]Entry, error) {
+	var models []CallModel
+	// the members are not needed for the entries
+	err := h.db.Omit("data").
+		Where("guild_id = ?", uint64(guildID)).
+		Order("started_at DESC").
+		Find(&models).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calls: %w", err)
+	}
+
+	entries := make([]Entry, len(models))
+	for i, m := range models {
+		entries[i] = m.toEntry()
+	}
+	return entries, nil
+}
+
+func (h *historyImpl) Load(guildID snowflake.ID, start time.Time, font *truetype.Font) (*Call, error) {
+	var model CallModel
+	if err := h.db.First(&model, "guild_id = ? AND started_at = ?", uint64(guildID), start.UnixMilli()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotInHistory
+		}
+		return nil, fmt.Errorf("failed to load call: %w", err)
+	}
+
+	var record callRecord
+	if err := json.Unmarshal(model.Data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal call: %w", err)
+	}
+	c := record.call()
+	c.Font = font
+	return c, nil
+}
+
+// callRecord is the stored representation of the call
+type callRecord struct {
+	Locale       discord.Locale `json:"locale"`
+	Rule         rule.Rule      `json:"rule"`
+	GuildID      snowflake.ID   `json:"guild_id"`
+	ChannelID    snowflake.ID   `json:"channel_id"`
+	ChannelName  string         `json:"channel_name"`
+	ChannelLabel string         `json:"channel_label"`
+	Branding     Branding       `json:"branding"`
+	Stage        bool           `json:"stage"`
+	Topic        string         `json:"topic,omitempty"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Members      []memberRecord `json:"members"`
+}
+
+type memberRecord struct {
+	ID        snowflake.ID    `json:"id"`
+	Name      string          `json:"name"`
+	Label     string          `json:"label"`
+	Avatar    cache.AvatarRef `json:"avatar"`
+	Duration  time.Duration   `json:"duration"`
+	Online    []onlineRecord  `json:"online"`
+	Streaming []sectionRecord `json:"streaming,omitempty"`
+	Video     []sectionRecord `json:"video,omitempty"`
+	Speaker   []sectionRecord `json:"speaker,omitempty"`
+}
+
+type sectionRecord struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type onlineRecord struct {
+	sectionRecord
+	Mute bool `json:"mute,omitempty"`
+	Deaf bool `json:"deaf,omitempty"`
+}
+
+func newCallRecord(c *Call) callRecord {
+	members := make([]memberRecord, len(c.Members))
+	for i, m := range c.Members {
+		members[i] = newMemberRecord(m)
+	}
+
+	return callRecord{
+		Locale:       c.Locale,
+		Rule:         c.Rule,
+		GuildID:      c.GuildID,
+		ChannelID:    c.ChannelID,
+		ChannelName:  c.ChannelName,
+		ChannelLabel: c.ChannelLabel,
+		Branding:     c.Branding,
+		Stage:        c.Stage,
+		Topic:        c.Topic,
+		Start:        c.Start,
+		End:          c.End,
+		Members:      members,
+	}
+}
+
+func (r callRecord) call() *Call {
+	c := &Call{
+		Locale:       r.Locale,
+		Rule:         r.Rule,
+		GuildID:      r.GuildID,
+		ChannelID:    r.ChannelID,
+		ChannelName:  r.ChannelName,
+		ChannelLabel: r.ChannelLabel,
+		Branding:     r.Branding,
+		Stage:        r.Stage,
+		Topic:        r.Topic,
+		Start:        r.Start,
+		End:          r.End,
+		Members:      make([]*Member, len(r.Members)),
+		MemberMap:    make(map[snowflake.ID]*Member, len(r.Members)),
+	}
+	for i, record := range r.Members {
+		m := record.member()
+		c.Members[i] = m
+		c.MemberMap[m.id] = m
+	}
+	return c
+}
+
+func newMemberRecord(m *Member) memberRecord {
+	online := make([]onlineRecord, len(m.onlineSections))
+	for i, s := range m.onlineSections {
+		online[i] = onlineRecord{sectionRecord: newSectionRecord(s.section), Mute: s.mute, Deaf: s.deaf}
+	}
+
+	return memberRecord{
+		ID:        m.id,
+		Name:      m.name,
+		Label:     m.label,
+		Avatar:    m.avatar,
+		Duration:  m.duration,
+		Online:    online,
+		Streaming: newSectionRecords(m.streamingSections),
+		Video:     newSectionRecords(m.videoSections),
+		Speaker:   newSectionRecords(m.speakerSections),
+	}
+}
+
+func (r memberRecord) member() *Member {
+	m := NewMember(r.ID, r.Name, r.Label, r.Avatar)
+	m.duration = r.Duration
+	for _, s := range r.Online {
+		m.onlineSections = append(m.onlineSections, sectionWithStatus{section: s.section(), mute: s.Mute, deaf: s.Deaf})
+	}
+	m.streamingSections = sectionsFromRecords(r.Streaming)
+	m.videoSections = sectionsFromRecords(r.Video)
+	m.speakerSections = sectionsFromRecords(r.Speaker)
+	return m
+}
+
+func newSectionRecord(s section) sectionRecord {
+	return sectionRecord{Start: s.start, End: s.end}
+}
+
+func (r sectionRecord) section() section {
+	return section{start: r.Start, end: r.End}
+}
+
+func newSectionRecords(sections []section) []sectionRecord {
+	records := make([]sectionRecord, len(sections))
+	for i, s := range sections {
+		records[i] = newSectionRecord(s)
+	}
+	return records
+}
+
+func sectionsFromRecords(records []sectionRecord) []section {
+	sections := make([]section, len(records))
+	for i, r := range records {
+		sections[i] = r.section()
+	}
+	return sections
+}
+
+// formatStart formats the start time to tell the calls in the same channel apart
+func formatStart(l discord.Locale, start time.Time) string {
+	return start.UTC().Format(locale.Get(l).Notification.Stats.StartFormat)
+}
@@ -0,0 +1,114 @@
+package call
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newTestHistory(t *testing.T) History {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CreateHistory(db)
+}
+
+func endedCall(guildID snowflake.ID, start time.Time) *Call {
+	c := &Call{
+		GuildID:      guildID,
+		ChannelID:    10,
+		ChannelLabel: "general",
+		Start:        start,
+		End:          start.Add(30 * time.Minute),
+		MemberMap:    make(map[snowflake.ID]*Member),
+	}
+
+	m := NewMember(1, "ringring", "ringring", cache.AvatarRef{UserID: 1})
+	m.MarkAsOnline(start, false, false)
+	m.UpdateStatus(start.Add(5*time.Minute), true, false)
+	m.MarkAsVideo(start.Add(10 * time.Minute))
+	m.UnmarkAsVideo(start.Add(20 * time.Minute))
+	m.UnmarkAsOnline(start.Add(30 * time.Minute))
+	c.Members = append(c.Members, m)
+	c.MemberMap[m.id] = m
+	return c
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	h := newTestHistory(t)
+	start := time.UnixMilli(1700000000123)
+	saved := endedCall(1, start)
+	assert.NoError(t, h.Save(saved))
+
+	loaded, err := h.Load(1, start, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, saved.ChannelLabel, loaded.ChannelLabel)
+	assert.True(t, saved.Start.Equal(loaded.Start))
+	assert.True(t, saved.End.Equal(loaded.End))
+
+	end := saved.End
+	assert.Equal(t, saved.Members[0].Stats(end), loaded.MemberMap[1].Stats(end))
+	assert.Len(t, loaded.MemberMap[1].onlineSections, 2)
+	assert.True(t, loaded.MemberMap[1].onlineSections[1].mute)
+
+	_, err = h.Load(2, start, nil)
+	assert.ErrorIs(t, err, ErrNotInHistory)
+}
+
+func TestHistoryKeepsLatestCalls(t *testing.T) {
+	h := newTestHistory(t)
+	start := time.UnixMilli(1700000000000)
+	for i := 0; i < maxHistory+5; i++ {
+		assert.NoError(t, h.Save(endedCall(1, start.Add(time.Duration(i)*time.Hour))))
+	}
+
+	entries, err := h.Entries(1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, maxHistory)
+	// from the latest one
+	assert.True(t, start.Add(time.Duration(maxHistory+4)*time.Hour).Equal(entries[0].Start))
+	assert.True(t, start.Add(5*time.Hour).Equal(entries[len(entries)-1].Start))
+}
+
+func TestSummaryFitsChoices(t *testing.T) {
+	locale.Init("../../../locales")
+	start := time.UnixMilli(1700000000000)
+	c := endedCall(1, start)
+	c.ChannelLabel = strings.Repeat("long channel name ", 10)
+
+	ongoing := *c
+	ongoing.End = time.Time{}
+	summary := ongoing.Summary(discord.LocaleEnglishUS, start.Add(time.Minute))
+	assert.Equal(t, maxChoiceName, utf8.RuneCountInString(summary))
+	assert.True(t, strings.HasPrefix(summary, "Nov 14 22:13 UTC"))
+
+	assert.Equal(t, maxChoiceName, utf8.RuneCountInString(c.Summary(discord.LocaleJapanese, start)))
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	c := endedCall(1, start)
+	end := c.End
+	cp := c.clone()
+
+	// the original goes on while the copy is read
+	m := c.MemberMap[1]
+	m.MarkAsOnline(end, false, false)
+	m.MarkAsStreaming(end)
+	c.Members = append(c.Members, NewMember(2, "other", "other", cache.AvatarRef{UserID: 2}))
+
+	assert.Len(t, cp.Members, 1)
+	assert.Same(t, cp.Members[0], cp.MemberMap[1])
+	assert.Len(t, cp.MemberMap[1].onlineSections, 2)
+	assert.False(t, cp.MemberMap[1].HasStreamed())
+}
@@ -0,0 +1,206 @@
+package call
+
+import (
+	"image"
+	"image/color"
+	"time"
+)
+
+// canvas is the minimal set of drawing primitives needed to draw a timeline.
+// it is implemented by the svg and the raster renderer, so both share the same layout.
+type canvas interface {
+	rect(x, y, w, h float64, c color.Color)
+	line(x1, y1, x2, y2 float64, c color.Color)
+	text(x, y float64, s string, c color.Color, anchor textAnchor)
+	image(x, y, size float64, img image.Image)
+}
+
+type textAnchor int
+
+const (
+	anchorStart textAnchor = iota
+	anchorMiddle
+)
+
+type layout struct {
+	padding       float64
+	avatarSize    float64
+	avatarMargin  float64
+	rowGap        float64
+	axisHeight    float64
+	timelineWidth float64
+	// labelWidth is zero when member names are not displayed
+	labelWidth float64
+	// legendHeight is zero when the legend is not displayed
+	legendHeight float64
+}
+
+func defaultLayout() layout {
+	return layout{
+		padding:       16,
+		avatarSize:    48,
+		avatarMargin:  12,
+		rowGap:        12,
+		axisHeight:    28,
+		timelineWidth: 640,
+	}
+}
+
+func (l layout) timelineLeft() float64 {
+	return l.padding + l.avatarSize + l.avatarMargin + l.labelWidth
+}
+
+func (l layout) rowTop(i int) float64 {
+	return l.padding + l.axisHeight + float64(i)*(l.avatarSize+l.rowGap)
+}
+
+func (l layout) size(entries int) (w, h float64) {
+	w = l.timelineLeft() + l.timelineWidth + l.padding
+	h = l.rowsBottom(entries) + l.legendHeight + l.padding
+	return w, h
+}
+
+func (l layout) rowsBottom(entries int) float64 {
+	if entries == 0 {
+		return l.rowTop(0)
+	}
+	return l.rowTop(entries) - l.rowGap
+}
+
+// ticsSteps are the candidates of the distance between two tics, from the finest.
+var ticsSteps = []time.Duration{
+	1 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	1 * time.Hour,
+	2 * time.Hour,
+	3 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+}
+
+const maxTics = 8
+
+func tics(start, end time.Time) []time.Time {
+	span := end.Sub(start)
+	step := ticsSteps[len(ticsSteps)-1]
+	for _, s := range ticsSteps {
+		if span/s <= maxTics {
+			step = s
+			break
+		}
+	}
+
+	result := make([]time.Time, 0, maxTics+1)
+	for t := start.Truncate(step); !t.After(end); t = t.Add(step) {
+		if t.Before(start) {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+const maxLabelLength = 16
+
+type legendItem struct {
+	label string
+	color color.Color
+}
+
+func drawTimeline(cv canvas, l layout, th theme, data timelineData) {
+	left := l.timelineLeft()
+	span := data.end.Sub(data.start)
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return left
+		}
+		if t.Before(data.start) {
+			t = data.start
+		}
+		if t.After(data.end) {
+			t = data.end
+		}
+		return left + l.timelineWidth*float64(t.Sub(data.start))/float64(span)
+	}
+
+	w, h := l.size(len(data.entries))
+	cv.rect(0, 0, w, h, th.background)
+
+	bottom := l.rowsBottom(len(data.entries))
+	axis := l.padding + l.axisHeight
+	cv.line(left, axis, left+l.timelineWidth, axis, th.grid)
+	for _, t := range tics(data.start, data.end) {
+		cv.line(x(t), axis-4, x(t), bottom, th.grid)
+		cv.text(x(t), axis-8, t.Format("15:04"), th.foreground, anchorMiddle)
+	}
+
+	for i, entry := range data.entries {
+		top := l.rowTop(i)
+		cv.image(l.padding, top, l.avatarSize, entry.avatar)
+
+		if l.labelWidth > 0 {
+			cv.text(l.padding+l.avatarSize+l.avatarMargin, top+l.avatarSize/2+4, truncate(entry.label, maxLabelLength), th.foreground, anchorStart)
+		}
+
+		// series are stacked and centered vertically next to the avatar
+		total := 0.0
+		for _, series := range entry.series {
+			total += series.height*l.avatarSize + 2
+		}
+		offset := (l.avatarSize - total) / 2
+		for _, series := range entry.series {
+			height := series.height * l.avatarSize
+			for _, section := range series.sections {
+				cv.rect(x(section.start), top+offset, x(section.end)-x(section.start), height, withAlpha(series.color, section.alpha))
+			}
+			offset += height + 2
+		}
+	}
+
+	if data.indicator != nil {
+		cv.line(x(*data.indicator), axis, x(*data.indicator), bottom, th.indicator)
+	}
+
+	if l.legendHeight > 0 {
+		lx := left
+		ly := bottom + l.legendHeight/2
+		for _, item := range data.legend {
+			cv.rect(lx, ly-6, 24, 10, item.color)
+			cv.text(lx+30, ly+4, item.label, th.foreground, anchorStart)
+			lx += 30 + estimateWidth(item.label) + 24
+		}
+	}
+}
+
+// estimateWidth roughly estimates the width of the text,
+// since svg cannot measure it before it is displayed.
+func estimateWidth(s string) float64 {
+	w := 0.0
+	for _, r := range s {
+		if r >= 0x1100 {
+			// wide characters, e.g. CJK
+			w += 13
+		} else {
+			w += 7.5
+		}
+	}
+	return w
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func withAlpha(c color.Color, alpha float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(float64(n.A) * alpha)
+	return n
+}
@@ -2,6 +2,8 @@ package call
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/disgo/rest"
@@ -13,12 +15,15 @@ type Manager interface {
 	Add(call *Call, now time.Time) (Handler, error)
 	Remove(channelID snowflake.ID)
 	Get(channelID snowflake.ID) (Handler, bool)
+	// Calls returns the copies of the ongoing calls in the guild, from the latest one
+	Calls(guildID snowflake.ID) []*Call
 }
 
 var _ Manager = (*managerImpl)(nil)
 
 type managerImpl struct {
 	rest     rest.Rest
+	mu       sync.RWMutex
 	handlers map[snowflake.ID]Handler
 }
 
@@ -37,16 +42,39 @@ func (m *managerImpl) Add(call *Call, now time.Time) (Handler, error) {
 		return nil, fmt.Errorf("failed to create handler: %w", err)
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.handlers[call.ChannelID] = handler
 	return handler, nil
 
 }
 
 func (m *managerImpl) Remove(channelID snowflake.ID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	delete(m.handlers, channelID)
 }
 
 func (m *managerImpl) Get(channelID snowflake.ID) (Handler, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	call, ok := m.handlers[channelID]
 	return call, ok
 }
+
+func (m *managerImpl) Calls(guildID snowflake.ID) []*Call {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	calls := make([]*Call, 0)
+	for _, handler := range m.handlers {
+		// the handler may be closed in the meantime
+		if c := handler.Snapshot(); c != nil && c.GuildID == guildID {
+			calls = append(calls, c)
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].Start.After(calls[j].Start)
+	})
+	return calls
+}
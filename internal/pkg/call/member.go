@@ -1,19 +1,25 @@
 package call
 
 import (
+	"slices"
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
 )
 
 type Member struct {
 	id                snowflake.ID
 	name              string
+	label             string
+	avatar            cache.AvatarRef
 	online            bool
 	lastUpdate        time.Time
 	duration          time.Duration
 	onlineSections    []sectionWithStatus
 	streamingSections []section
+	videoSections     []section
+	speakerSections   []section
 }
 
 type section struct {
@@ -31,18 +37,43 @@ func (s sectionWithStatus) IsSameStatus(other sectionWithStatus) bool {
 	return s.mute == other.mute && s.deaf == other.deaf
 }
 
-func NewMember(userID snowflake.ID, name string) *Member {
+// NewMember creates a new Member.
+// name is formatted by the rule, and label is the plain name drawn in the timeline.
+// clone copies the member with the sections, so that the copy can be read while the member is updated
+func (m *Member) clone() *Member {
+	c := *m
+	c.onlineSections = slices.Clone(m.onlineSections)
+	c.streamingSections = slices.Clone(m.streamingSections)
+	c.videoSections = slices.Clone(m.videoSections)
+	c.speakerSections = slices.Clone(m.speakerSections)
+	return &c
+}
+
+func NewMember(userID snowflake.ID, name, label string, avatar cache.AvatarRef) *Member {
 	return &Member{
 		id:                userID,
 		name:              name,
+		label:             label,
+		avatar:            avatar,
 		online:            false,
 		lastUpdate:        time.Time{},
 		duration:          0,
 		onlineSections:    make([]sectionWithStatus, 0),
 		streamingSections: make([]section, 0),
+		videoSections:     make([]section, 0),
+		speakerSections:   make([]section, 0),
 	}
 }
 
+func (m *Member) ID() snowflake.ID {
+	return m.id
+}
+
+// Label returns the plain name of the member
+func (m *Member) Label() string {
+	return m.label
+}
+
 func (m *Member) MarkAsOnline(now time.Time, muted, deaf bool) {
 	if m.online {
 		panic("member already online")
@@ -89,6 +120,18 @@ func (m *Member) UnmarkAsOnline(now time.Time) {
 	m.onlineSections[l-1].end = now
 }
 
+// leave ends every section of the member still open
+func (m *Member) leave(now time.Time) {
+	if m.online {
+		m.UnmarkAsOnline(now)
+	}
+	for _, sections := range [][]section{m.streamingSections, m.videoSections, m.speakerSections} {
+		if l := len(sections); l > 0 && sections[l-1].end.IsZero() {
+			sections[l-1].end = now
+		}
+	}
+}
+
 func (m *Member) MarkAsStreaming(now time.Time) {
 	m.streamingSections = append(m.streamingSections, section{start: now})
 }
@@ -106,6 +149,81 @@ func (m *Member) HasStreamed() bool {
 	return len(m.streamingSections) > 0
 }
 
+func (m *Member) MarkAsVideo(now time.Time) {
+	m.videoSections = append(m.videoSections, section{start: now})
+}
+
+func (m *Member) UnmarkAsVideo(now time.Time) {
+	if len(m.videoSections) == 0 {
+		panic("member not using video")
+	}
+
+	l := len(m.videoSections)
+	m.videoSections[l-1].end = now
+}
+
+func (m *Member) HasUsedVideo() bool {
+	return len(m.videoSections) > 0
+}
+
+// MarkAsSpeaker marks the member as a speaker of the stage.
+// it is safe to call it for a member who is already a speaker.
+func (m *Member) MarkAsSpeaker(now time.Time) {
+	if m.IsSpeaker() {
+		return
+	}
+	m.speakerSections = append(m.speakerSections, section{start: now})
+}
+
+// UnmarkAsSpeaker moves the member back to the audience of the stage.
+// it is safe to call it for a member who is not a speaker.
+func (m *Member) UnmarkAsSpeaker(now time.Time) {
+	if !m.IsSpeaker() {
+		return
+	}
+
+	l := len(m.speakerSections)
+	m.speakerSections[l-1].end = now
+}
+
+func (m *Member) IsSpeaker() bool {
+	l := len(m.speakerSections)
+	return l > 0 && m.speakerSections[l-1].end.IsZero()
+}
+
+func (m *Member) HasSpoken() bool {
+	return len(m.speakerSections) > 0
+}
+
+// Stats is the summary of how a member participated in the call
+type Stats struct {
+	Online    time.Duration
+	Streaming time.Duration
+	Video     time.Duration
+	Speaker   time.Duration
+}
+
+func (m Member) Stats(now time.Time) Stats {
+	return Stats{
+		Online:    m.calculateDuration(now),
+		Streaming: sumSections(m.streamingSections, now),
+		Video:     sumSections(m.videoSections, now),
+		Speaker:   sumSections(m.speakerSections, now),
+	}
+}
+
+func sumSections(sections []section, now time.Time) time.Duration {
+	var d time.Duration
+	for _, s := range sections {
+		end := s.end
+		if end.IsZero() {
+			end = now
+		}
+		d += end.Sub(s.start)
+	}
+	return d
+}
+
 func (m Member) calculateDuration(now time.Time) time.Duration {
 	if m.online {
 		return m.duration + now.Sub(m.lastUpdate)
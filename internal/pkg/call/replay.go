@@ -0,0 +1,152 @@
+package call
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	replayFrames = 24
+	// delays are in 100ths of a second
+	replayFrameDelay     = 15
+	replayLastFrameDelay = 300
+)
+
+var _ canvas = (*rasterCanvas)(nil)
+
+type rasterCanvas struct {
+	dst  *image.RGBA
+	face font.Face
+}
+
+func newRasterCanvas(w, h float64, face font.Face) *rasterCanvas {
+	dst := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(w)), int(math.Ceil(h))))
+	return &rasterCanvas{dst: dst, face: face}
+}
+
+func (cv *rasterCanvas) rect(x, y, w, h float64, c color.Color) {
+	r := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h)))
+	draw.Draw(cv.dst, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// line only supports horizontal and vertical lines, which is all the timeline needs.
+func (cv *rasterCanvas) line(x1, y1, x2, y2 float64, c color.Color) {
+	if x1 == x2 {
+		cv.rect(x1, math.Min(y1, y2), 1, math.Abs(y2-y1), c)
+		return
+	}
+	cv.rect(math.Min(x1, x2), y1, math.Abs(x2-x1), 1, c)
+}
+
+func (cv *rasterCanvas) text(x, y float64, s string, c color.Color, anchor textAnchor) {
+	d := &font.Drawer{
+		Dst:  cv.dst,
+		Src:  image.NewUniform(c),
+		Face: cv.face,
+	}
+	d.Dot = fixed.Point26_6{X: fixed.I(int(x)), Y: fixed.I(int(y))}
+	if anchor == anchorMiddle {
+		d.Dot.X -= d.MeasureString(s) / 2
+	}
+	d.DrawString(s)
+}
+
+func (cv *rasterCanvas) image(x, y, size float64, img image.Image) {
+	r := image.Rect(int(x), int(y), int(x+size), int(y+size))
+	draw.CatmullRom.Scale(cv.dst, r, img, img.Bounds(), draw.Over, nil)
+}
+
+// renderRaster renders the timeline as png with the same layout as svg.
+// it is used instead of the design library when the rule customizes the appearance.
+func (c *Call) renderRaster(data timelineData) (io.Reader, error) {
+	l := c.layout()
+	w, h := l.size(len(data.entries))
+	face := truetype.NewFace(c.Font, &truetype.Options{Size: 13})
+
+	cv := newRasterCanvas(w, h, face)
+	drawTimeline(cv, l, themeOf(c.Rule.TimelineTheme), data)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fitImage(cv.dst, c.Rule.TimelineMaxSize)); err != nil {
+		return nil, fmt.Errorf("failed to encode timeline: %w", err)
+	}
+	return &buf, nil
+}
+
+// renderReplay renders an animated gif which replays the call from the start to the end of the frame.
+func (c *Call) renderReplay(data timelineData) (io.Reader, error) {
+	l := c.layout()
+	th := themeOf(c.Rule.TimelineTheme)
+	w, h := l.size(len(data.entries))
+	face := truetype.NewFace(c.Font, &truetype.Options{Size: 13})
+
+	anim := &gif.GIF{}
+	span := data.end.Sub(data.start)
+	for i := 1; i <= replayFrames; i++ {
+		at := data.start.Add(span * time.Duration(i) / replayFrames)
+
+		cv := newRasterCanvas(w, h, face)
+		drawTimeline(cv, l, th, data.until(at))
+
+		src := fitImage(cv.dst, c.Rule.TimelineMaxSize)
+		frame := image.NewPaletted(src.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), src, src.Bounds().Min, draw.Src)
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, replayFrameDelay)
+	}
+	anim.Delay[len(anim.Delay)-1] = replayLastFrameDelay
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, fmt.Errorf("failed to encode replay: %w", err)
+	}
+	return &buf, nil
+}
+
+// until returns a copy of the data which only contains what happened before t.
+// the frame is kept, so the timeline does not rescale while replaying.
+func (d timelineData) until(t time.Time) timelineData {
+	clipped := timelineData{
+		start:     d.start,
+		end:       d.end,
+		indicator: &t,
+		legend:    d.legend,
+		entries:   make([]timelineEntry, len(d.entries)),
+	}
+
+	for i, entry := range d.entries {
+		e := entry
+		e.series = make([]timelineSeries, len(entry.series))
+		for j, series := range entry.series {
+			s := series
+			s.sections = make([]timelineSection, 0, len(series.sections))
+			for _, section := range series.sections {
+				if section.start.After(t) {
+					continue
+				}
+				if section.end.After(t) {
+					section.end = t
+				}
+				s.sections = append(s.sections, section)
+			}
+			e.series[j] = s
+		}
+		clipped.entries[i] = e
+	}
+
+	return clipped
+}
@@ -0,0 +1,71 @@
+package call
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+var _ canvas = (*svgCanvas)(nil)
+
+type svgCanvas struct {
+	buf *bytes.Buffer
+}
+
+func (c *Call) renderSVG(data timelineData) io.Reader {
+	l := c.layout()
+	w, h := l.size(len(data.entries))
+
+	// svg is scalable, so only the displayed size is limited
+	dw, dh := w, h
+	if limit := float64(c.Rule.TimelineMaxSize); limit > 0 && (w > limit || h > limit) {
+		scale := math.Min(limit/w, limit/h)
+		dw, dh = w*scale, h*scale
+	}
+
+	cv := &svgCanvas{buf: &bytes.Buffer{}}
+	fmt.Fprintf(cv.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="13">`, dw, dh, w, h)
+	drawTimeline(cv, l, themeOf(c.Rule.TimelineTheme), data)
+	cv.buf.WriteString("</svg>")
+
+	return cv.buf
+}
+
+func (cv *svgCanvas) rect(x, y, w, h float64, c color.Color) {
+	fill, opacity := svgColor(c)
+	fmt.Fprintf(cv.buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" fill-opacity="%.2f"/>`, x, y, w, h, fill, opacity)
+}
+
+func (cv *svgCanvas) line(x1, y1, x2, y2 float64, c color.Color) {
+	stroke, opacity := svgColor(c)
+	fmt.Fprintf(cv.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-opacity="%.2f"/>`, x1, y1, x2, y2, stroke, opacity)
+}
+
+func (cv *svgCanvas) text(x, y float64, s string, c color.Color, anchor textAnchor) {
+	fill, opacity := svgColor(c)
+	textAnchor := "start"
+	if anchor == anchorMiddle {
+		textAnchor = "middle"
+	}
+	fmt.Fprintf(cv.buf, `<text x="%.1f" y="%.1f" fill="%s" fill-opacity="%.2f" text-anchor="%s">%s</text>`, x, y, fill, opacity, textAnchor, html.EscapeString(s))
+}
+
+func (cv *svgCanvas) image(x, y, size float64, img image.Image) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		// an avatar which cannot be encoded is just skipped
+		return
+	}
+	fmt.Fprintf(cv.buf, `<image x="%.1f" y="%.1f" width="%.1f" height="%.1f" href="data:image/png;base64,%s"/>`, x, y, size, size, base64.StdEncoding.EncodeToString(b.Bytes()))
+}
+
+func svgColor(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B), float64(n.A) / 0xFF
+}
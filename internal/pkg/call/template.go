@@ -0,0 +1,92 @@
+package call
+
+import (
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+)
+
+// limits of the embeds by discord
+const (
+	maxTitleLength       = 256
+	maxDescriptionLength = 4096
+	maxFooterLength      = 2048
+)
+
+// templateArgs are the values of the template variables
+func (c *Call) templateArgs(state string, now time.Time) locale.Args {
+	starter := ""
+	if len(c.Members) > 0 {
+		starter = c.Members[0].name
+	}
+
+	return locale.Args{
+		rule.TemplateVariableChannel:      c.ChannelName,
+		rule.TemplateVariableStarter:      starter,
+		rule.TemplateVariableParticipants: len(c.Members),
+		rule.TemplateVariableDuration:     localizeDuration(c.Locale, c.elapsed(now), false),
+		rule.TemplateVariableStart:        discord.FormattedTimestampMention(c.Start.Unix(), discord.TimestampStyleShortTime),
+		rule.TemplateVariableState:        state,
+	}
+}
+
+// applyTemplate overrides the embed with the fields of the template set by the rule
+func (c *Call) applyTemplate(builder *discord.EmbedBuilder, state string, now time.Time) {
+	t := c.Rule.Template
+	if t.IsEmpty() {
+		return
+	}
+
+	args := c.templateArgs(state, now)
+	if t.Title != "" {
+		builder.SetTitle(truncate(locale.Format(c.Locale, t.Title, args), maxTitleLength))
+	}
+	if t.Description != "" {
+		builder.SetDescription(truncate(locale.Format(c.Locale, t.Description, args), maxDescriptionLength))
+	}
+	if t.Color != "" {
+		// the default color is kept if the template is broken
+		if color, err := rule.ParseColor(locale.Format(c.Locale, t.Color, args)); err == nil {
+			builder.SetColor(color)
+		}
+	}
+	if t.Footer != "" {
+		builder.SetFooterText(truncate(locale.Format(c.Locale, t.Footer, args), maxFooterLength))
+	}
+}
+
+// PreviewEmbeds shows how the ongoing and ended notifications look like with the rule,
+// on a sample call in the channel started by the member of the branding.
+func PreviewEmbeds(l discord.Locale, r rule.Rule, channelID snowflake.ID, channelName string, branding Branding, now time.Time) []discord.Embed {
+	start := now.Add(-83 * time.Minute)
+	c := &Call{
+		Locale:       l,
+		Rule:         r,
+		ChannelID:    channelID,
+		ChannelName:  channelName,
+		ChannelLabel: channelName,
+		Start:        start,
+		End:          now,
+		MemberMap:    make(map[snowflake.ID]*Member),
+		Branding:     branding,
+	}
+
+	for i, name := range []string{"ringring", "makeitchaccha"} {
+		m := NewMember(snowflake.ID(i+1), name, name, cache.AvatarRef{})
+		m.MarkAsOnline(start.Add(time.Duration(i)*10*time.Minute), false, false)
+		m.UnmarkAsOnline(now)
+		c.Members = append(c.Members, m)
+		c.MemberMap[m.id] = m
+	}
+
+	embeds := []discord.Embed{c.OngoingEmbed(now), c.EndedEmbed()}
+	for i := range embeds {
+		// the timeline is not attached to the preview
+		embeds[i].Image = nil
+	}
+	return embeds
+}
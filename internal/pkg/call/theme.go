@@ -0,0 +1,91 @@
+package call
+
+import (
+	"image/color"
+
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+)
+
+type theme struct {
+	background color.Color
+	foreground color.Color
+	grid       color.Color
+	indicator  color.Color
+
+	// luminance is the target luminance of the color extracted from the avatar
+	luminance float64
+	// palette replaces the colors extracted from the avatars, if not empty
+	palette []color.Color
+
+	muteAlpha float64
+	deafAlpha float64
+	streaming color.Color
+	video     color.Color
+	speaker   color.Color
+}
+
+func themeOf(t rule.TimelineTheme) theme {
+	switch t {
+	case rule.TimelineThemeDark:
+		return theme{
+			background: color.RGBA{R: 0x31, G: 0x33, B: 0x38, A: 0xFF},
+			foreground: color.RGBA{R: 0xDB, G: 0xDE, B: 0xE1, A: 0xFF},
+			grid:       color.RGBA{R: 0x6D, G: 0x6F, B: 0x78, A: 0xFF},
+			indicator:  color.RGBA{R: 0xF2, G: 0x3F, B: 0x43, A: 0xFF},
+			luminance:  0.75,
+			muteAlpha:  0.7,
+			deafAlpha:  0.45,
+			streaming:  color.RGBA{R: 0xF2, G: 0x3F, B: 0x43, A: 0xFF},
+			video:      color.RGBA{R: 0x23, G: 0xA5, B: 0x5A, A: 0xFF},
+			speaker:    color.RGBA{R: 0xF0, G: 0xB2, B: 0x32, A: 0xFF},
+		}
+	case rule.TimelineThemeHighContrast:
+		return theme{
+			background: color.White,
+			foreground: color.Black,
+			grid:       color.Black,
+			indicator:  color.RGBA{R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+			luminance:  0.45,
+			muteAlpha:  0.6,
+			deafAlpha:  0.3,
+			streaming:  color.RGBA{R: 0xC0, G: 0x00, B: 0xC0, A: 0xFF},
+			video:      color.RGBA{R: 0x00, G: 0x80, B: 0x00, A: 0xFF},
+			speaker:    color.RGBA{R: 0x00, G: 0x00, B: 0xFF, A: 0xFF},
+		}
+	case rule.TimelineThemeColorblind:
+		// Okabe-Ito palette, distinguishable with every common kind of color blindness
+		return theme{
+			background: color.White,
+			foreground: color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xFF},
+			grid:       color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF},
+			indicator:  color.Black,
+			palette: []color.Color{
+				color.RGBA{R: 0x00, G: 0x72, B: 0xB2, A: 0xFF},
+				color.RGBA{R: 0xE6, G: 0x9F, B: 0x00, A: 0xFF},
+				color.RGBA{R: 0x00, G: 0x9E, B: 0x73, A: 0xFF},
+				color.RGBA{R: 0xCC, G: 0x79, B: 0xA7, A: 0xFF},
+				color.RGBA{R: 0x56, G: 0xB4, B: 0xE9, A: 0xFF},
+				color.RGBA{R: 0xF0, G: 0xE4, B: 0x42, A: 0xFF},
+			},
+			muteAlpha: 0.65,
+			deafAlpha: 0.35,
+			streaming: color.RGBA{R: 0xD5, G: 0x5E, B: 0x00, A: 0xFF},
+			video:     color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+			speaker:   color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xFF},
+		}
+	default:
+		// same as the timeline before themes were introduced
+		return theme{
+			background: color.White,
+			foreground: color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF},
+			grid:       color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF},
+			indicator:  color.RGBA{R: 0xE0, G: 0x40, B: 0x40, A: 0xFF},
+			luminance:  0.7,
+			muteAlpha:  0.8,
+			deafAlpha:  0.6,
+			streaming:  color.RGBA64{R: 0xB000, G: 0x0000, B: 0x0000, A: 0xFFFF},
+			video:      color.RGBA64{R: 0x0000, G: 0x8000, B: 0x3000, A: 0xFFFF},
+			speaker:    color.RGBA64{R: 0xD000, G: 0x9000, B: 0x0000, A: 0xFFFF},
+		}
+	}
+}
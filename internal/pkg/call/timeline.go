@@ -0,0 +1,310 @@
+package call
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/golang/freetype/truetype"
+	"github.com/makeitchaccha/design/timeline"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+	"github.com/makeitchaccha/ringring/internal/pkg/util"
+)
+
+// timelineData is the format independent representation of a timeline.
+// every renderer (png, svg, replay) is fed with the same data.
+type timelineData struct {
+	start     time.Time
+	end       time.Time
+	indicator *time.Time
+	entries   []timelineEntry
+	legend    []legendItem
+}
+
+type timelineEntry struct {
+	label  string
+	avatar image.Image
+	series []timelineSeries
+}
+
+type timelineSeries struct {
+	// height is relative to the height of the entry
+	height   float64
+	color    color.Color
+	sections []timelineSection
+}
+
+type timelineSection struct {
+	start time.Time
+	end   time.Time
+	alpha float64
+}
+
+type generateOptions struct {
+	indicator *time.Time
+	format    rule.TimelineFormat
+}
+
+type GenerateOptions func(o *generateOptions)
+
+func WithIndicator(indicator time.Time) GenerateOptions {
+	return func(o *generateOptions) {
+		o.indicator = &indicator
+	}
+}
+
+// WithFormat overrides the format selected by the rule,
+// e.g. when the timeline is exported on demand.
+func WithFormat(format rule.TimelineFormat) GenerateOptions {
+	return func(o *generateOptions) {
+		o.format = format
+	}
+}
+
+func (c *Call) GenerateTimeline(rest rest.Rest, now time.Time, frame time.Time, opts ...GenerateOptions) (*discord.File, error) {
+	o := &generateOptions{format: c.Rule.TimelineFormat}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data := c.timelineData(rest, now, frame)
+	data.indicator = o.indicator
+
+	switch o.format {
+	case rule.TimelineFormatPNG:
+		render := c.renderRaster
+		if c.isClassicAppearance() {
+			render = c.renderPNG
+		}
+		r, err := render(data)
+		if err != nil {
+			return nil, err
+		}
+		return &discord.File{
+			Name:   o.format.Filename(),
+			Reader: r,
+		}, nil
+	case rule.TimelineFormatSVG:
+		return &discord.File{
+			Name:   o.format.Filename(),
+			Reader: c.renderSVG(data),
+		}, nil
+	case rule.TimelineFormatReplay:
+		r, err := c.renderReplay(data)
+		if err != nil {
+			return nil, err
+		}
+		return &discord.File{
+			Name:   o.format.Filename(),
+			Reader: r,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported timeline format: %s", o.format)
+}
+
+// isClassicAppearance reports whether the timeline looks the same as the design library renders,
+// which cannot change the background, nor draw the legend and member names.
+func (c *Call) isClassicAppearance() bool {
+	return c.Rule.TimelineTheme == rule.TimelineThemeLight && !c.Rule.TimelineLegend && !c.Rule.TimelineLabels
+}
+
+func (c *Call) layout() layout {
+	l := defaultLayout()
+	if c.Rule.TimelineLabels {
+		l.labelWidth = 130
+	}
+	if c.Rule.TimelineLegend {
+		l.legendHeight = 32
+	}
+	return l
+}
+
+func (c *Call) timelineData(rest rest.Rest, now time.Time, frame time.Time) timelineData {
+	th := themeOf(c.Rule.TimelineTheme)
+	data := timelineData{
+		start:   c.Start,
+		end:     frame,
+		entries: make([]timelineEntry, 0, len(c.Members)+1),
+	}
+
+	if c.Rule.TimelineLegend {
+		l := locale.Get(c.Locale).Notification.Timeline.Legend
+		data.legend = []legendItem{
+			{label: l.Online, color: th.grid},
+			{label: l.Mute, color: withAlpha(th.grid, th.muteAlpha)},
+			{label: l.Deaf, color: withAlpha(th.grid, th.deafAlpha)},
+			{label: l.Streaming, color: th.streaming},
+			{label: l.Video, color: th.video},
+		}
+		if c.Stage {
+			data.legend = append(data.legend, legendItem{label: l.Speaker, color: th.speaker})
+		}
+	}
+
+	members, others := c.splitMembers(now)
+	avatars := c.fetchAvatars(rest, members)
+
+	colors := memberColors(th, avatars)
+
+	for i, m := range members {
+		avatar := avatars[i]
+		entry := timelineEntry{
+			label:  m.label,
+			avatar: avatar,
+		}
+		main := colors[i]
+
+		online := timelineSeries{
+			height: 2.0 / 7.0,
+			color:  main,
+		}
+		for _, log := range m.onlineSections {
+			if log.end.IsZero() {
+				log.end = now
+			}
+			alpha := 1.0
+			if log.mute {
+				alpha = th.muteAlpha
+			}
+			if log.deaf {
+				alpha = th.deafAlpha
+			}
+			online.sections = append(online.sections, timelineSection{start: log.start, end: log.end, alpha: alpha})
+		}
+		entry.series = append(entry.series, online)
+
+		if m.HasStreamed() {
+			entry.series = append(entry.series, timelineSeries{
+				height:   0.5 / 7.0,
+				color:    th.streaming,
+				sections: sectionsOf(m.streamingSections, now),
+			})
+		}
+
+		if m.HasUsedVideo() {
+			entry.series = append(entry.series, timelineSeries{
+				height:   0.5 / 7.0,
+				color:    th.video,
+				sections: sectionsOf(m.videoSections, now),
+			})
+		}
+
+		if m.HasSpoken() {
+			entry.series = append(entry.series, timelineSeries{
+				height:   0.5 / 7.0,
+				color:    th.speaker,
+				sections: sectionsOf(m.speakerSections, now),
+			})
+		}
+
+		data.entries = append(data.entries, entry)
+	}
+
+	if len(others) > 0 {
+		data.entries = append(data.entries, c.othersEntry(others, now, th))
+	}
+
+	return data
+}
+
+func sectionsOf(sections []section, now time.Time) []timelineSection {
+	result := make([]timelineSection, 0, len(sections))
+	for _, log := range sections {
+		if log.end.IsZero() {
+			log.end = now
+		}
+		result = append(result, timelineSection{start: log.start, end: log.end, alpha: 1.0})
+	}
+	return result
+}
+
+// renderPNG renders the timeline with the design library.
+func (c *Call) renderPNG(data timelineData) (io.Reader, error) {
+	builder := timeline.NewTimelineBuilder(data.start, data.end)
+
+	if data.indicator != nil {
+		builder.SetIndicator(*data.indicator)
+	}
+
+	for _, entry := range data.entries {
+		e := timeline.NewEntryBuilder(entry.avatar)
+		for _, series := range entry.series {
+			s := timeline.NewSeriesBuilder(series.height, series.color)
+			for _, section := range series.sections {
+				s.AddSection(section.start, section.end, timeline.WithAlpha(section.alpha))
+			}
+			e.AddSeries(s.Build())
+		}
+		builder.AddEntries(e.Build())
+	}
+
+	// prevent generated timeline from being too long in the vertical direction
+	// w:h = 4:3 is used in the thumbnail
+	layout := timeline.DefaultLayout()
+	if h, w := builder.Height(), builder.Width(); 4*h > 3*w {
+		// adjust width to fit the timeline
+		dw := 4*h/3 - w
+		layout.TimelineWidth += dw
+	}
+
+	builder.SetLayout(layout)
+
+	// font modification
+	ticsFont := truetype.NewFace(c.Font, &truetype.Options{
+		Size: 15,
+	})
+
+	builder.MainTics.Label.Font = ticsFont
+	builder.SubTics.Label.Font = ticsFont
+
+	r := builder.Build().Generate()
+	if c.Rule.TimelineMaxSize <= 0 {
+		return r, nil
+	}
+
+	// the design library does not know the limit, so the generated image is scaled afterwards
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode timeline: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fitImage(img, c.Rule.TimelineMaxSize)); err != nil {
+		return nil, fmt.Errorf("failed to encode timeline: %w", err)
+	}
+	return &buf, nil
+}
+
+// minColorDistance is the minimum Lab distance between the colors of members in the same call
+const minColorDistance = 0.15
+
+// memberColors picks a color for each avatar.
+// the dominant colors of the avatar are preferred, but members in the same call always get visually distinct colors.
+func memberColors(th theme, avatars []image.Image) []color.Color {
+	if len(th.palette) > 0 {
+		colors := make([]color.Color, len(avatars))
+		for i := range avatars {
+			colors[i] = th.palette[i%len(th.palette)]
+		}
+		return colors
+	}
+
+	candidates := make([][]color.Color, len(avatars))
+	for i, avatar := range avatars {
+		dominant := util.ExtractDominantColors(avatar)
+		if len(dominant) == 0 {
+			dominant = []color.Color{color.Black}
+		}
+		for _, c := range dominant {
+			candidates[i] = append(candidates[i], util.TransformColorWithSpecificLuminance(c, th.luminance))
+		}
+	}
+	return util.AssignDistinctColors(candidates, minColorDistance)
+}
@@ -3,9 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/glebarez/sqlite"
 	"github.com/golang/freetype/truetype"
+	"github.com/makeitchaccha/ringring/internal/pkg/cache"
 	"golang.org/x/image/font/gofont/goregular"
 	"gopkg.in/yaml.v3"
 	"gorm.io/driver/mysql"
@@ -39,12 +41,27 @@ type configDTO struct {
 		Token string `yaml:"token"`
 		Font  string `yaml:"font"`
 	} `yaml:"discord"`
+
+	Cache struct {
+		Avatar struct {
+			Size     int    `yaml:"size"`
+			TTL      string `yaml:"ttl"`
+			Dir      string `yaml:"dir"`
+			DiskSize int    `yaml:"disk-size"`
+		} `yaml:"avatar"`
+	} `yaml:"cache"`
 }
 
 type Config struct {
 	Dialector gorm.Dialector
 	Token     string
 	Font      *truetype.Font
+
+	AvatarCacheSize int
+	AvatarCacheTTL  time.Duration
+	// AvatarCacheDir is empty when avatars are not persisted
+	AvatarCacheDir      string
+	AvatarCacheDiskSize int
 }
 
 func New(path string) (*Config, error) {
@@ -85,10 +102,40 @@ func New(path string) (*Config, error) {
 		font = f
 	}
 
+	avatarCacheSize := cache.DefaultAvatarCacheSize
+	if raw.Cache.Avatar.Size != 0 {
+		if raw.Cache.Avatar.Size < 0 {
+			return nil, fmt.Errorf("invalid avatar cache size: %d", raw.Cache.Avatar.Size)
+		}
+		avatarCacheSize = raw.Cache.Avatar.Size
+	}
+
+	avatarCacheTTL := cache.DefaultAvatarCacheTTL
+	if raw.Cache.Avatar.TTL != "" {
+		ttl, err := time.ParseDuration(raw.Cache.Avatar.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avatar cache ttl: %w", err)
+		}
+		avatarCacheTTL = ttl
+	}
+
+	avatarCacheDiskSize := cache.DefaultAvatarCacheDiskSize
+	if raw.Cache.Avatar.DiskSize != 0 {
+		if raw.Cache.Avatar.DiskSize < 0 {
+			return nil, fmt.Errorf("invalid avatar cache disk size: %d", raw.Cache.Avatar.DiskSize)
+		}
+		avatarCacheDiskSize = raw.Cache.Avatar.DiskSize
+	}
+
 	cfg := &Config{
 		Dialector: getDialector(raw.Database.Driver, raw.Database.DSN),
 		Token:     raw.Discord.Token,
 		Font:      font,
+
+		AvatarCacheSize:     avatarCacheSize,
+		AvatarCacheTTL:      avatarCacheTTL,
+		AvatarCacheDir:      raw.Cache.Avatar.Dir,
+		AvatarCacheDiskSize: avatarCacheDiskSize,
 	}
 
 	return cfg, nil
@@ -126,6 +173,7 @@ func overrideWithEnv(cfg *configDTO) bool {
 	overridden = overrideString("DATABASE_DRIVER", &cfg.Database.Driver) || overridden
 	overridden = overrideString("DATABASE_DSN", &cfg.Database.DSN) || overridden
 	overridden = overrideString("DISCORD_FONT", &cfg.Discord.Font) || overridden
+	overridden = overrideString("AVATAR_CACHE_DIR", &cfg.Cache.Avatar.Dir) || overridden
 
 	return overridden
 }
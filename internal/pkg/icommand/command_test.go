@@ -0,0 +1,77 @@
+package icommand
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/stretchr/testify/assert"
+)
+
+const localesDir = "../../../locales"
+
+// localeFiles lists the locales from the files, not from the locale package
+func localeFiles(t *testing.T) []discord.Locale {
+	files, err := os.ReadDir(localesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locales := make([]discord.Locale, 0, len(files))
+	for _, file := range files {
+		name, _ := strings.CutSuffix(file.Name(), filepath.Ext(file.Name()))
+		locales = append(locales, discord.Locale(name))
+	}
+	return locales
+}
+
+func TestCommandsAreLocalized(t *testing.T) {
+	locale.Init(localesDir)
+	locales := localeFiles(t)
+
+	commands := []discord.ApplicationCommandCreate{
+		(&Settings{}).Create(),
+		(&Stats{}).Create(),
+		(&History{}).Create(),
+	}
+
+	for _, command := range commands {
+		slash, ok := command.(discord.SlashCommandCreate)
+		if !ok {
+			t.Fatalf("unexpected command type: %T", command)
+		}
+		assertLocalized(t, locales, "/"+slash.Name, slash.NameLocalizations, slash.DescriptionLocalizations)
+		assertOptionsLocalized(t, locales, "/"+slash.Name, slash.Options)
+	}
+}
+
+func assertOptionsLocalized(t *testing.T, locales []discord.Locale, path string, options []discord.ApplicationCommandOption) {
+	for _, option := range options {
+		switch o := option.(type) {
+		case discord.ApplicationCommandOptionSubCommandGroup:
+			assertLocalized(t, locales, path+"/"+o.Name, o.NameLocalizations, o.DescriptionLocalizations)
+			for _, sub := range o.Options {
+				assertOptionsLocalized(t, locales, path+"/"+o.Name, []discord.ApplicationCommandOption{sub})
+			}
+		case discord.ApplicationCommandOptionSubCommand:
+			assertLocalized(t, locales, path+"/"+o.Name, o.NameLocalizations, o.DescriptionLocalizations)
+			assertOptionsLocalized(t, locales, path+"/"+o.Name, o.Options)
+		case discord.ApplicationCommandOptionChannel:
+			assertLocalized(t, locales, path+" "+o.Name, o.NameLocalizations, o.DescriptionLocalizations)
+		case discord.ApplicationCommandOptionString:
+			assertLocalized(t, locales, path+" "+o.Name, o.NameLocalizations, o.DescriptionLocalizations)
+		default:
+			t.Errorf("%s: unexpected option type: %T", path, option)
+		}
+	}
+}
+
+func assertLocalized(t *testing.T, locales []discord.Locale, path string, names, descriptions map[discord.Locale]string) {
+	for _, l := range locales {
+		assert.NotEmpty(t, names[l], "%s: no name for %s", path, l)
+		assert.NotEmpty(t, descriptions[l], "%s: no description for %s", path, l)
+	}
+}
@@ -0,0 +1,233 @@
+package icommand
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/golang/freetype/truetype"
+	"github.com/makeitchaccha/ringring/internal/pkg/call"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+	"github.com/makeitchaccha/ringring/pkg/command"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
+)
+
+var _ command.Autocompleter = (*History)(nil)
+
+const historyCommandName = "history"
+
+// History looks back on the ended calls stored in the history
+type History struct {
+	History call.History
+	// Font is used to render the timelines again
+	Font *truetype.Font
+}
+
+func (h *History) router() *command.Router {
+	return command.NewRouter(discord.SlashCommandCreate{
+		Name: historyCommandName,
+		NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.History.Name
+		}),
+		Description: locale.Get(discord.LocaleEnglishUS).Command.History.Description,
+		DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.History.Description
+		}),
+	}).SubCommand(
+		&historyExport{history: h},
+	)
+}
+
+func (h *History) Name() string {
+	return historyCommandName
+}
+
+func (h *History) Create() discord.ApplicationCommandCreate {
+	return h.router().Create()
+}
+
+func (h *History) Execute(event *events.ApplicationCommandInteractionCreate) error {
+	if event.GuildID() == nil {
+		return middleware.NewUserError(locale.Get(event.Locale()).Error.GuildOnly)
+	}
+
+	return h.router().Execute(event)
+}
+
+func (h *History) Autocomplete(event *events.AutocompleteInteractionCreate) error {
+	if event.GuildID() == nil {
+		return event.AutocompleteResult(nil)
+	}
+
+	return h.router().Autocomplete(event)
+}
+
+// choices lists the ended calls of the guild whose channel contains the query, the value is the start time
+func (h *History) choices(l discord.Locale, guildID snowflake.ID, query string) ([]discord.AutocompleteChoice, error) {
+	entries, err := h.History.Entries(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	choices := make([]discord.AutocompleteChoice, 0, maxChoices)
+	for _, e := range entries {
+		if len(choices) == maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(e.ChannelLabel), query) {
+			continue
+		}
+		choices = append(choices, discord.AutocompleteChoiceString{
+			Name:  e.Summary(l),
+			Value: startValue(e.Start),
+		})
+	}
+	return choices, nil
+}
+
+// find loads the ended call of the guild from the value of the call option
+func (h *History) find(guildID snowflake.ID, value string) (*call.Call, error) {
+	start, ok := parseStartValue(value)
+	if !ok {
+		return nil, call.ErrNotInHistory
+	}
+	return h.History.Load(guildID, start, h.Font)
+}
+
+// startValue is the value of the choices to pick a call by the start time
+func startValue(start time.Time) string {
+	return strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+func parseStartValue(value string) (time.Time, bool) {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(millis), true
+}
+
+var _ command.SubCommandAutocompleter = (*historyExport)(nil)
+
+// historyExport renders the timeline of an ended call in the format chosen
+type historyExport struct {
+	history *History
+}
+
+func (c *historyExport) Create() discord.ApplicationCommandOptionSubCommand {
+	formats := []rule.TimelineFormat{rule.TimelineFormatPNG, rule.TimelineFormatSVG, rule.TimelineFormatReplay}
+	choices := make([]discord.ApplicationCommandOptionChoiceString, len(formats))
+	for i, format := range formats {
+		choices[i] = discord.ApplicationCommandOptionChoiceString{
+			Name: locale.Get(discord.LocaleEnglishUS).Command.History.SubCommands.Export.Options.Format.Values[format.String()],
+			NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.History.SubCommands.Export.Options.Format.Values[format.String()]
+			}),
+			Value: format.String(),
+		}
+	}
+
+	return discord.ApplicationCommandOptionSubCommand{
+		Name: "export",
+		NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.History.SubCommands.Export.Name
+		}),
+		Description: locale.Get(discord.LocaleEnglishUS).Command.History.SubCommands.Export.Description,
+		DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.History.SubCommands.Export.Description
+		}),
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name: "call",
+				NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.History.SubCommands.Export.Options.Call.Name
+				}),
+				Description: locale.Get(discord.LocaleEnglishUS).Command.History.SubCommands.Export.Options.Call.Description,
+				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.History.SubCommands.Export.Options.Call.Description
+				}),
+				Required:     true,
+				Autocomplete: true,
+			},
+			discord.ApplicationCommandOptionString{
+				Name: "format",
+				NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.History.SubCommands.Export.Options.Format.Name
+				}),
+				Description: locale.Get(discord.LocaleEnglishUS).Command.History.SubCommands.Export.Options.Format.Description,
+				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.History.SubCommands.Export.Options.Format.Description
+				}),
+				Choices: choices,
+			},
+		},
+	}
+}
+
+func (c *historyExport) Execute(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	l := locale.Get(event.Locale()).Command.History
+
+	found, err := c.history.find(*event.GuildID(), data.String("call"))
+	if errors.Is(err, call.ErrNotInHistory) {
+		return middleware.NewUserError(l.Error.CallNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	format := found.Rule.TimelineFormat
+	if value, ok := data.OptString("format"); ok {
+		format = rule.ParseTimelineFormat(value)
+	}
+
+	// rendering the replay takes a while
+	if err := event.DeferCreateMessage(true); err != nil {
+		return err
+	}
+
+	// the events are dispatched one by one, so the rendering must not block the others
+	go c.export(event, found, format)
+	return nil
+}
+
+// export renders the timeline and sends it as the response deferred
+func (c *historyExport) export(event *events.ApplicationCommandInteractionCreate, found *call.Call, format rule.TimelineFormat) {
+	content := locale.Format(event.Locale(), locale.Get(event.Locale()).Command.History.SubCommands.Export.Content, locale.Args{
+		"call": found.Entry().Summary(event.Locale()),
+	})
+	update := discord.NewMessageUpdateBuilder().SetContent(content)
+
+	file, err := found.GenerateTimeline(event.Client().Rest(), found.End, found.End, call.WithFormat(format))
+	if err != nil {
+		// the interaction is already responded, so the error is shown here
+		fmt.Fprintln(os.Stderr, "failed to export timeline:", err)
+		update.SetContent(locale.Get(event.Locale()).Error.Internal)
+	} else {
+		update.AddFiles(file)
+	}
+
+	if _, err := event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), update.Build()); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to send exported timeline:", err)
+	}
+}
+
+func (c *historyExport) Autocomplete(event *events.AutocompleteInteractionCreate, data discord.AutocompleteInteractionData) error {
+	focused := data.Focused()
+	if focused.Name != "call" {
+		return event.AutocompleteResult(nil)
+	}
+
+	choices, err := c.history.choices(event.Locale(), *event.GuildID(), data.String(focused.Name))
+	if err != nil {
+		return err
+	}
+	return event.AutocompleteResult(choices)
+}
@@ -0,0 +1,120 @@
+package icommand
+
+import (
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/pkg/command"
+)
+
+var _ command.SubCommand = (*settingsPreview)(nil)
+
+// settingsPreview shows how the bot would work on each voice channel
+type settingsPreview struct {
+	settings *Settings
+}
+
+func (c *settingsPreview) Create() discord.ApplicationCommandOptionSubCommand {
+	return discord.ApplicationCommandOptionSubCommand{
+		Name: "preview",
+		NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.Settings.SubCommands.Preview.Name
+		}),
+		Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Preview.Description,
+		DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.Settings.SubCommands.Preview.Description
+		}),
+	}
+}
+
+func (c *settingsPreview) Execute(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	p := locale.Get(event.Locale()).Command.Settings.SubCommands.Preview
+
+	// just send a preview message
+	embeds := c.generatePreview(event)
+
+	if len(embeds) == 0 {
+		return event.CreateMessage(discord.NewMessageCreateBuilder().
+			SetContent(p.Empty).
+			SetEphemeral(true).
+			Build(),
+		)
+	}
+
+	// split the embeds into 10 embeds per message
+	err := event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetContent(p.Content).
+		SetEphemeral(true).
+		Build(),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(embeds); i += 10 {
+		end := i + 10
+		if end > len(embeds) {
+			end = len(embeds)
+		}
+
+		_, err := event.Client().Rest().CreateMessage(
+			event.Channel().ID(),
+			discord.NewMessageCreateBuilder().
+				SetEmbeds(embeds[i:end]...).
+				Build(),
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *settingsPreview) generatePreview(event *events.ApplicationCommandInteractionCreate) []discord.Embed {
+	f := locale.Get(event.Locale()).Form.Settings.Fields
+	p := locale.Get(event.Locale()).Command.Settings.SubCommands.Preview
+	channels, err := event.Client().Rest().GetGuildChannels(*event.GuildID())
+	if err != nil {
+		return []discord.Embed{
+			discord.NewEmbedBuilder().
+				SetTitle(p.Error).
+				SetDescription(err.Error()).
+				Build(),
+		}
+	}
+
+	embeds := make([]discord.Embed, 0)
+	for _, channel := range channels {
+		if channel.Type() != discord.ChannelTypeGuildVoice && channel.Type() != discord.ChannelTypeGuildStageVoice {
+			continue
+		}
+
+		builder := discord.NewEmbedBuilder()
+		rule, scope := c.settings.Rule.ScopedEffectiveRule(*event.GuildID(), channel.ParentID(), channel.ID())
+
+		if !rule.Enabled {
+			builder.SetTitlef("❌ %s", channel.Name())
+			builder.SetDescription(p.Disabled)
+			builder.SetColor(0xff0000)
+			builder.AddField(p.Scope.Title, p.Scope.Values[scope.String()], true)
+		} else {
+			builder.SetTitlef("✅ %s", channel.Name())
+			builder.SetDescription(p.Enabled)
+			builder.SetColor(0x00ff00)
+			builder.AddField(p.Scope.Title, p.Scope.Values[scope.String()], true)
+			builder.AddField(f.NotificationChannel.Title, discord.ChannelMention(rule.NotificationChannel), true)
+			builder.AddField(f.ChannelFormat.Title, f.ChannelFormat.Values[rule.ChannelFormat.String()], true)
+			builder.AddField(f.History.Title, f.History.Values[rule.History.String()], true)
+			if rule.History.ShouldDisplayName() {
+				builder.AddField(f.UsernameFormat.Title, f.UsernameFormat.Values[rule.UserFormat.String()], true)
+			}
+		}
+
+		embeds = append(embeds, builder.Build())
+	}
+
+	return embeds
+}
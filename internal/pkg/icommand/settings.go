@@ -1,161 +1,154 @@
 package icommand
 
 import (
-	"fmt"
-
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/json"
+	"github.com/makeitchaccha/ringring/internal/pkg/call"
 	"github.com/makeitchaccha/ringring/internal/pkg/iform"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"github.com/makeitchaccha/ringring/internal/pkg/rule"
 	"github.com/makeitchaccha/ringring/pkg/command"
 	"github.com/makeitchaccha/ringring/pkg/form"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
 )
 
 var _ command.Command = (*Settings)(nil)
 
+const settingsCommandName = "ringring"
+
 type Settings struct {
 	Form form.Manager
 	Rule rule.Repository
 }
 
-func (s *Settings) Name() string {
-	return "ringring"
-}
-
-func (s *Settings) Create() discord.ApplicationCommandCreate {
-	return discord.SlashCommandCreate{
-		Name:        s.Name(),
+func (s *Settings) router() *command.Router {
+	return command.NewRouter(discord.SlashCommandCreate{
+		Name: settingsCommandName,
+		NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.Settings.Name
+		}),
 		Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.Description,
 		DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
 			return entry.Command.Settings.Description
 		}),
 		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
-
-		Options: []discord.ApplicationCommandOption{
-			discord.ApplicationCommandOptionSubCommand{
-				Name:        "guild",
-				Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Guild.Description,
-				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
-					return entry.Command.Settings.SubCommands.Guild.Description
-				}),
-			},
-			discord.ApplicationCommandOptionSubCommand{
-				Name:        "category",
-				Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Category.Description,
-				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
-					return entry.Command.Settings.SubCommands.Category.Description
-				}),
-				Options: []discord.ApplicationCommandOption{
-					discord.ApplicationCommandOptionChannel{
-						Name:        "category",
-						Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Category.Options.Category.Description,
-						DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
-							return entry.Command.Settings.SubCommands.Category.Options.Category.Description
-						}),
-						Required: true,
-						ChannelTypes: []discord.ChannelType{
-							discord.ChannelTypeGuildCategory,
-						},
-					},
-				},
-			},
-			discord.ApplicationCommandOptionSubCommand{
-				Name:        "channel",
-				Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Channel.Description,
-				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
-					return entry.Command.Settings.SubCommands.Channel.Description
-				}),
-				Options: []discord.ApplicationCommandOption{
-					discord.ApplicationCommandOptionChannel{
-						Name:        "channel",
-						Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Channel.Options.Channel.Description,
-						DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
-							return entry.Command.Settings.SubCommands.Channel.Options.Channel.Description
-						}),
-						Required: true,
-						ChannelTypes: []discord.ChannelType{
-							discord.ChannelTypeGuildVoice, discord.ChannelTypeGuildStageVoice,
-						},
-					},
-				},
-			},
-			discord.ApplicationCommandOptionSubCommand{
-				Name:        "preview",
-				Description: "preview the voice channels with how the bot would works",
-			},
-		},
-	}
+	}).SubCommand(
+		&settingsScope{settings: s, scope: rule.ScopeGuild},
+		&settingsScope{settings: s, scope: rule.ScopeCategory},
+		&settingsScope{settings: s, scope: rule.ScopeChannel},
+		&settingsPreview{settings: s},
+	)
 }
 
-func (s *Settings) Execute(event *events.ApplicationCommandInteractionCreate) error {
-
-	data := event.ApplicationCommandInteraction.SlashCommandInteractionData()
+func (s *Settings) Name() string {
+	return settingsCommandName
+}
 
-	if data.SubCommandName == nil {
-		return fmt.Errorf("subcommand not found")
-	}
+func (s *Settings) Create() discord.ApplicationCommandCreate {
+	return s.router().Create()
+}
 
+func (s *Settings) Execute(event *events.ApplicationCommandInteractionCreate) error {
 	if event.GuildID() == nil {
-		return fmt.Errorf("command is not available in DM")
+		return middleware.NewUserError(locale.Get(event.Locale()).Error.GuildOnly)
 	}
 
-	if *data.SubCommandName == "preview" {
-		// just send a preview message
-		embeds := s.generatePreview(event)
+	return s.router().Execute(event)
+}
 
-		if len(embeds) == 0 {
-			return event.CreateMessage(discord.NewMessageCreateBuilder().
-				SetContent("No voice channels found").
-				SetEphemeral(true).
-				Build(),
-			)
-		}
+var _ command.SubCommand = (*settingsScope)(nil)
 
-		// split the embeds into 10 embeds per message
-		err := event.CreateMessage(discord.NewMessageCreateBuilder().
-			SetContent("Preview of how the bot would works").
-			SetEphemeral(true).
-			Build(),
-		)
+// settingsScope shows the settings form of the scope
+type settingsScope struct {
+	settings *Settings
+	scope    rule.Scope
+}
 
-		if err != nil {
-			return err
+func (c *settingsScope) Create() discord.ApplicationCommandOptionSubCommand {
+	switch c.scope {
+	case rule.ScopeCategory:
+		return discord.ApplicationCommandOptionSubCommand{
+			Name: "category",
+			NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Category.Name
+			}),
+			Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Category.Description,
+			DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Category.Description
+			}),
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionChannel{
+					Name: "category",
+					NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+						return entry.Command.Settings.SubCommands.Category.Options.Category.Name
+					}),
+					Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Category.Options.Category.Description,
+					DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+						return entry.Command.Settings.SubCommands.Category.Options.Category.Description
+					}),
+					Required: true,
+					ChannelTypes: []discord.ChannelType{
+						discord.ChannelTypeGuildCategory,
+					},
+				},
+			},
 		}
-
-		for i := 0; i < len(embeds); i += 10 {
-			end := i + 10
-			if end > len(embeds) {
-				end = len(embeds)
-			}
-
-			_, err := event.Client().Rest().CreateMessage(
-				event.Channel().ID(),
-				discord.NewMessageCreateBuilder().
-					SetEmbeds(embeds[i:end]...).
-					Build(),
-			)
-
-			if err != nil {
-				return err
-			}
+	case rule.ScopeChannel:
+		return discord.ApplicationCommandOptionSubCommand{
+			Name: "channel",
+			NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Channel.Name
+			}),
+			Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Channel.Description,
+			DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Channel.Description
+			}),
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionChannel{
+					Name: "channel",
+					NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+						return entry.Command.Settings.SubCommands.Channel.Options.Channel.Name
+					}),
+					Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Channel.Options.Channel.Description,
+					DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+						return entry.Command.Settings.SubCommands.Channel.Options.Channel.Description
+					}),
+					Required: true,
+					ChannelTypes: []discord.ChannelType{
+						discord.ChannelTypeGuildVoice, discord.ChannelTypeGuildStageVoice,
+					},
+				},
+			},
+		}
+	default:
+		return discord.ApplicationCommandOptionSubCommand{
+			Name: "guild",
+			NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Guild.Name
+			}),
+			Description: locale.Get(discord.LocaleEnglishUS).Command.Settings.SubCommands.Guild.Description,
+			DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+				return entry.Command.Settings.SubCommands.Guild.Description
+			}),
 		}
-
-		return nil
 	}
+}
+
+func (c *settingsScope) Execute(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	s := c.settings
 
 	var form *iform.Rule
 
-	switch *data.SubCommandName {
-	case "guild":
+	switch c.scope {
+	case rule.ScopeGuild:
 		form = iform.GuildRule(event.User().ID, s.Rule, event.Locale(), *event.GuildID())
 		if rule, ok := s.Rule.FindGuildRule(*event.GuildID()); ok {
 			form.HasDeleteButton = true
 			form.Apply(rule)
 		}
 
-	case "category":
+	case rule.ScopeCategory:
 		category := data.Channel("category")
 		form = iform.CategoryRule(event.User().ID, s.Rule, event.Locale(), category.ID)
 		if rule, ok := s.Rule.FindCategoryRule(category.ID); ok {
@@ -163,7 +156,7 @@ func (s *Settings) Execute(event *events.ApplicationCommandInteractionCreate) er
 			form.Apply(rule)
 		}
 
-	case "channel":
+	case rule.ScopeChannel:
 		channel := data.Channel("channel")
 		form = iform.ChannelRule(event.User().ID, s.Rule, event.Locale(), channel.ID)
 		if rule, ok := s.Rule.FindChannelRule(channel.ID); ok {
@@ -172,60 +165,25 @@ func (s *Settings) Execute(event *events.ApplicationCommandInteractionCreate) er
 		}
 	}
 
-	err := s.Form.Send(event.Channel().ID(), form)
-
-	if err != nil {
-		return err
-	}
+	form.Branding = branding(event)
 
-	return event.CreateMessage(discord.NewMessageCreateBuilder().
-		SetContent(locale.Get(event.Locale()).Command.Settings.Response.ShowForm).
-		SetEphemeral(true).
-		Build(),
-	)
+	// the settings are shown only to the user who ran the command
+	return s.Form.Reply(event, form)
 }
 
-func (s *Settings) generatePreview(event *events.ApplicationCommandInteractionCreate) []discord.Embed {
-	f := locale.Get(event.Locale()).Form.Settings.Fields
-	channels, err := event.Client().Rest().GetGuildChannels(*event.GuildID())
-	if err != nil {
-		return []discord.Embed{
-			discord.NewEmbedBuilder().
-				SetTitle("failed to get channels, make sure the bot has permission to view channels").
-				SetDescription(err.Error()).
-				Build(),
-		}
+// branding resolves the branding of the preview as if the user started the call
+func branding(event *events.ApplicationCommandInteractionCreate) call.Branding {
+	member := event.Member()
+	if member == nil {
+		return call.Branding{}
 	}
 
-	embeds := make([]discord.Embed, 0)
-	for _, channel := range channels {
-		if channel.Type() != discord.ChannelTypeGuildVoice && channel.Type() != discord.ChannelTypeGuildStageVoice {
-			continue
-		}
-
-		builder := discord.NewEmbedBuilder()
-		rule, scope := s.Rule.ScopedEffectiveRule(*event.GuildID(), channel.ParentID(), channel.ID())
-
-		if !rule.Enabled {
-			builder.SetTitlef("❌ %s", channel.Name())
-			builder.SetDescription("通知が無効化されています")
-			builder.SetColor(0xff0000)
-			builder.AddField("スコープ", scope.String(), true)
-		} else {
-			builder.SetTitlef("✅ %s", channel.Name())
-			builder.SetDescription("通知が有効化されています")
-			builder.SetColor(0x00ff00)
-			builder.AddField("スコープ", scope.String(), true)
-			builder.AddField(f.NotificationChannel.Title, discord.ChannelMention(rule.NotificationChannel), true)
-			builder.AddField(f.ChannelFormat.Title, f.ChannelFormat.Values[rule.ChannelFormat.String()], true)
-			builder.AddField(f.History.Title, f.History.Values[rule.History.String()], true)
-			if rule.History.ShouldDisplayName() {
-				builder.AddField(f.UsernameFormat.Title, f.UsernameFormat.Values[rule.UserFormat.String()], true)
-			}
-		}
+	m := member.Member
+	m.GuildID = *event.GuildID()
 
-		embeds = append(embeds, builder.Build())
+	var guild *discord.Guild
+	if g, ok := event.Client().Caches().Guild(m.GuildID); ok {
+		guild = &g
 	}
-
-	return embeds
+	return call.NewBranding(m, event.Client().Caches().MemberRoles(m), guild)
 }
@@ -0,0 +1,187 @@
+package icommand
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/call"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+	"github.com/makeitchaccha/ringring/pkg/command"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
+)
+
+var _ command.Autocompleter = (*Stats)(nil)
+
+const statsCommandName = "stats"
+
+// maxChoices is the number of choices discord accepts for autocomplete
+const maxChoices = 25
+
+// Stats shows how a member joined an ongoing or ended call,
+// the call and the member are picked with autocomplete.
+type Stats struct {
+	Call    call.Manager
+	History call.History
+}
+
+func (s *Stats) Name() string {
+	return statsCommandName
+}
+
+func (s *Stats) Create() discord.ApplicationCommandCreate {
+	return discord.SlashCommandCreate{
+		Name: statsCommandName,
+		NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.Stats.Name
+		}),
+		Description: locale.Get(discord.LocaleEnglishUS).Command.Stats.Description,
+		DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+			return entry.Command.Stats.Description
+		}),
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name: "call",
+				NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.Stats.Options.Call.Name
+				}),
+				Description: locale.Get(discord.LocaleEnglishUS).Command.Stats.Options.Call.Description,
+				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.Stats.Options.Call.Description
+				}),
+				Required:     true,
+				Autocomplete: true,
+			},
+			discord.ApplicationCommandOptionString{
+				Name: "member",
+				NameLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.Stats.Options.Member.Name
+				}),
+				Description: locale.Get(discord.LocaleEnglishUS).Command.Stats.Options.Member.Description,
+				DescriptionLocalizations: locale.Localizations(func(entry locale.Entry) string {
+					return entry.Command.Stats.Options.Member.Description
+				}),
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (s *Stats) Execute(event *events.ApplicationCommandInteractionCreate) error {
+	l := locale.Get(event.Locale()).Command.Stats
+	if event.GuildID() == nil {
+		return middleware.NewUserError(locale.Get(event.Locale()).Error.GuildOnly)
+	}
+
+	data := event.SlashCommandInteractionData()
+	c, err := s.find(*event.GuildID(), data.String("call"))
+	if errors.Is(err, call.ErrNotInHistory) {
+		return middleware.NewUserError(l.Error.CallNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	memberID := event.User().ID
+	if value, ok := data.OptString("member"); ok {
+		id, err := snowflake.Parse(value)
+		if err != nil {
+			return middleware.NewUserError(l.Error.MemberNotFound)
+		}
+		memberID = id
+	}
+
+	member, ok := c.MemberMap[memberID]
+	if !ok {
+		return middleware.NewUserError(l.Error.MemberNotFound)
+	}
+
+	return event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetEmbeds(c.StatsEmbed(event.Locale(), member, time.Now())).
+		SetEphemeral(true).
+		Build())
+}
+
+func (s *Stats) Autocomplete(event *events.AutocompleteInteractionCreate) error {
+	if event.GuildID() == nil {
+		return event.AutocompleteResult(nil)
+	}
+
+	focused := event.Data.Focused()
+	query := strings.ToLower(event.Data.String(focused.Name))
+	choices := make([]discord.AutocompleteChoice, 0, maxChoices)
+
+	switch focused.Name {
+	case "call":
+		now := time.Now()
+		// the ongoing calls come first, then the ended ones, from the latest one
+		for _, c := range s.Call.Calls(*event.GuildID()) {
+			if !strings.Contains(strings.ToLower(c.ChannelLabel), query) {
+				continue
+			}
+			choices = append(choices, discord.AutocompleteChoiceString{
+				Name:  c.Summary(event.Locale(), now),
+				Value: startValue(c.Start),
+			})
+		}
+
+		entries, err := s.History.Entries(*event.GuildID())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to list calls:", err)
+		}
+		for _, e := range entries {
+			if len(choices) == maxChoices {
+				break
+			}
+			if !strings.Contains(strings.ToLower(e.ChannelLabel), query) {
+				continue
+			}
+			choices = append(choices, discord.AutocompleteChoiceString{
+				Name:  e.Summary(event.Locale()),
+				Value: startValue(e.Start),
+			})
+		}
+
+	case "member":
+		c, err := s.find(*event.GuildID(), event.Data.String("call"))
+		if err != nil {
+			break
+		}
+		for _, m := range c.Members {
+			if !strings.Contains(strings.ToLower(m.Label()), query) {
+				continue
+			}
+			choices = append(choices, discord.AutocompleteChoiceString{
+				Name:  m.Label(),
+				Value: m.ID().String(),
+			})
+		}
+	}
+
+	if len(choices) > maxChoices {
+		choices = choices[:maxChoices]
+	}
+	return event.AutocompleteResult(choices)
+}
+
+// find finds the call in the guild from the value of the call option, which is the start time.
+// the ongoing calls are looked up first, then the history.
+func (s *Stats) find(guildID snowflake.ID, value string) (*call.Call, error) {
+	start, ok := parseStartValue(value)
+	if !ok {
+		return nil, call.ErrNotInHistory
+	}
+
+	for _, c := range s.Call.Calls(guildID) {
+		if c.Start.UnixMilli() == start.UnixMilli() {
+			return c, nil
+		}
+	}
+	// the timeline is not rendered, so the font is not needed
+	return s.History.Load(guildID, start, nil)
+}
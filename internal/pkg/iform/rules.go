@@ -3,25 +3,25 @@ package iform
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/call"
 	"github.com/makeitchaccha/ringring/internal/pkg/locale"
 	"github.com/makeitchaccha/ringring/internal/pkg/rule"
 	"github.com/makeitchaccha/ringring/pkg/extstd"
 	"github.com/makeitchaccha/ringring/pkg/form"
 )
 
-var _ form.Form = (*Rule)(nil)
-
-type confirm int
-
-const (
-	confirmNone confirm = iota
-	confirmSave
-	confirmDelete
+var (
+	_ form.Guarded    = (*Rule)(nil)
+	_ form.Finishable = (*Rule)(nil)
+	_ form.ModalForm  = (*Rule)(nil)
 )
 
 type Rule struct {
@@ -32,7 +32,13 @@ type Rule struct {
 	HasDeleteButton bool
 	Finalized       bool
 
-	confirm confirm
+	// pending is the button waiting for the confirmation
+	pending string
+	page    string
+
+	// base keeps the fields of the applied rule that the form does not edit,
+	// so saving the form never resets them.
+	base rule.Rule
 
 	Scope           rule.Scope
 	ScopeIdentifier snowflake.ID
@@ -43,6 +49,29 @@ type Rule struct {
 
 	Privacy        extstd.Option[rule.History]
 	UsernameFormat extstd.Option[rule.UserFormat]
+
+	TimelineFormat extstd.Option[rule.TimelineFormat]
+	TimelineTheme  extstd.Option[rule.TimelineTheme]
+	TimelineLegend form.Bool
+	TimelineLabels form.Bool
+
+	// zero means no limit
+	TimelineMaxMembers int
+	TimelineMaxSize    int
+
+	StageLiveOnly form.Bool
+
+	Template rule.Template
+
+	ColorSource extstd.Option[rule.ColorSource]
+	// none means rule.DefaultColor
+	OngoingColor extstd.Option[int]
+	EndedColor   extstd.Option[int]
+	Thumbnail    extstd.Option[rule.Thumbnail]
+	ThumbnailURL string
+
+	// Branding is used by the preview, resolved from the user who opened the form
+	Branding call.Branding
 }
 
 const (
@@ -51,14 +80,59 @@ const (
 	settingKeyUsernameFormat      = "uf"
 	settingKeyChannelFormat       = "cf"
 	settingKeyPrivacy             = "p"
+	settingKeyTimelineFormat      = "tf"
+	settingKeyTimelineTheme       = "tt"
+	settingKeyTimelineLegend      = "tlg"
+	settingKeyTimelineLabels      = "tlb"
+	settingKeyStageLiveOnly       = "slo"
 
 	settingButtonSave    = "bs"
 	settingButtonDiscard = "bdc"
 	settingButtonDelete  = "bdl"
 
-	settingButtonConfirmSave   = "bcs"
-	settingButtonConfirmDelete = "bcd"
-	settingButtonCancel        = "bc"
+	settingButtonTimelineLimits = "btl"
+	settingModalTimelineLimits  = "mtl"
+	settingKeyMaxMembers        = "mm"
+	settingKeyMaxSize           = "ms"
+
+	settingButtonTemplate         = "bte"
+	settingButtonResetTemplate    = "btr"
+	settingModalTemplate          = "mte"
+	settingKeyTemplateTitle       = "tti"
+	settingKeyTemplateDescription = "tde"
+	settingKeyTemplateColor       = "tco"
+	settingKeyTemplateFooter      = "tfo"
+
+	settingKeyColorSource  = "cs"
+	settingKeyThumbnail    = "th"
+	settingButtonBranding  = "bbr"
+	settingModalBranding   = "mbr"
+	settingKeyOngoingColor = "oc"
+	settingKeyEndedColor   = "ec"
+	settingKeyThumbnailURL = "tu"
+)
+
+const (
+	settingPageDelivery = "delivery"
+	settingPageDisplay  = "display"
+	settingPageTimeline = "timeline"
+	settingPageFilters  = "filters"
+	settingPageTemplate = "template"
+)
+
+const (
+	maxTimelineMembers = 50
+	minTimelineSize    = 256
+	maxTimelineSize    = 4096
+
+	// lengths of the template fields, the formatted ones are truncated to fit the embed
+	maxTemplateTitle       = 256
+	maxTemplateDescription = 1000
+	maxTemplateColor       = 200
+	maxTemplateFooter      = 1000
+
+	// discord rejects the longer urls of the embeds
+	maxThumbnailURL = 2048
 )
 
 func GuildRule(owner snowflake.ID, ruleManager rule.Repository, locale discord.Locale, guildID snowflake.ID) *Rule {
@@ -74,6 +148,10 @@ func GuildRule(owner snowflake.ID, ruleManager rule.Repository, locale discord.L
 		UsernameFormat:      extstd.None[rule.UserFormat](),
 		ChannelFormat:       extstd.None[rule.ChannelFormat](),
 		Privacy:             extstd.None[rule.History](),
+		TimelineFormat:      extstd.Some(rule.TimelineFormatPNG),
+		TimelineTheme:       extstd.Some(rule.TimelineThemeLight),
+		ColorSource:         extstd.Some(rule.ColorSourceFixed),
+		Thumbnail:           extstd.Some(rule.ThumbnailNone),
 	}
 }
 
@@ -90,6 +168,10 @@ func CategoryRule(owner snowflake.ID, ruleManager rule.Repository, locale discor
 		UsernameFormat:      extstd.None[rule.UserFormat](),
 		ChannelFormat:       extstd.None[rule.ChannelFormat](),
 		Privacy:             extstd.None[rule.History](),
+		TimelineFormat:      extstd.Some(rule.TimelineFormatPNG),
+		TimelineTheme:       extstd.Some(rule.TimelineThemeLight),
+		ColorSource:         extstd.Some(rule.ColorSourceFixed),
+		Thumbnail:           extstd.Some(rule.ThumbnailNone),
 	}
 }
 
@@ -106,22 +188,38 @@ func ChannelRule(owner snowflake.ID, ruleManager rule.Repository, locale discord
 		UsernameFormat:      extstd.None[rule.UserFormat](),
 		ChannelFormat:       extstd.None[rule.ChannelFormat](),
 		Privacy:             extstd.None[rule.History](),
+		TimelineFormat:      extstd.Some(rule.TimelineFormatPNG),
+		TimelineTheme:       extstd.Some(rule.TimelineThemeLight),
+		ColorSource:         extstd.Some(rule.ColorSourceFixed),
+		Thumbnail:           extstd.Some(rule.ThumbnailNone),
 	}
 }
 
 func (s *Rule) Apply(rule rule.Rule) {
+	s.base = rule
 	s.Enabled = form.Bool(rule.Enabled)
 	s.NotificationChannel = extstd.Some(rule.NotificationChannel)
 	s.ChannelFormat = extstd.Some(rule.ChannelFormat)
 	s.Privacy = extstd.Some(rule.History)
 	s.UsernameFormat = extstd.Some(rule.UserFormat)
+	s.TimelineMaxMembers = rule.TimelineMaxMembers
+	s.TimelineMaxSize = rule.TimelineMaxSize
+	s.TimelineFormat = extstd.Some(rule.TimelineFormat)
+	s.TimelineTheme = extstd.Some(rule.TimelineTheme)
+	s.TimelineLegend = form.Bool(rule.TimelineLegend)
+	s.TimelineLabels = form.Bool(rule.TimelineLabels)
+	s.StageLiveOnly = form.Bool(rule.StageLiveOnly)
+	s.Template = rule.Template
+	s.ColorSource = extstd.Some(rule.ColorSource)
+	s.OngoingColor = fromPtr(rule.OngoingColor)
+	s.EndedColor = fromPtr(rule.EndedColor)
+	s.Thumbnail = extstd.Some(rule.Thumbnail)
+	s.ThumbnailURL = rule.ThumbnailURL
 }
 
 func (s *Rule) Create() discord.MessageCreate {
 	builder := discord.NewMessageCreateBuilder().
-		SetEmbeds(
-			s.buildEmbed(""),
-		)
+		SetEmbeds(s.buildEmbeds("")...)
 
 	if !s.Finalized {
 		builder.AddContainerComponents(s.buildComponents()...)
@@ -132,9 +230,7 @@ func (s *Rule) Create() discord.MessageCreate {
 
 func (s *Rule) update(status string) discord.MessageUpdate {
 	builder := discord.NewMessageUpdateBuilder().
-		SetEmbeds(
-			s.buildEmbed(status),
-		)
+		SetEmbeds(s.buildEmbeds(status)...)
 
 	if !s.Finalized {
 		builder.SetContainerComponents(s.buildComponents()...)
@@ -145,6 +241,32 @@ func (s *Rule) update(status string) discord.MessageUpdate {
 	return builder.Build()
 }
 
+// buildEmbeds returns the embed of the settings,
+// followed by the previews of the notifications while the template is edited.
+func (s *Rule) buildEmbeds(status string) []discord.Embed {
+	embeds := []discord.Embed{s.buildEmbed(status)}
+	if s.page != settingPageTemplate || s.Finalized {
+		return embeds
+	}
+
+	r := s.base
+	r.History = s.Privacy.UnwrapOr(rule.HistoryNone)
+	r.Template = s.Template
+	r.ColorSource = s.ColorSource.UnwrapOr(rule.ColorSourceFixed)
+	r.OngoingColor = toPtr(s.OngoingColor)
+	r.EndedColor = toPtr(s.EndedColor)
+	r.Thumbnail = s.Thumbnail.UnwrapOr(rule.ThumbnailNone)
+	r.ThumbnailURL = s.ThumbnailURL
+
+	// the sample channel derives the colors from the scope if the colors are by channel
+	channelName := "#general"
+	if s.Scope == rule.ScopeChannel {
+		channelName = discord.ChannelMention(s.ScopeIdentifier)
+	}
+
+	return append(embeds, call.PreviewEmbeds(s.locale, r, s.ScopeIdentifier, channelName, s.Branding, time.Now())...)
+}
+
 func (s *Rule) buildEmbed(status string) discord.Embed {
 	e := locale.Get(s.locale).Form.Settings.Fields
 	builder := discord.NewEmbedBuilder().
@@ -154,7 +276,16 @@ func (s *Rule) buildEmbed(status string) discord.Embed {
 		AddField(e.NotificationChannel.Title, discord.ChannelMention(s.NotificationChannel.UnwrapOr(0)), true).
 		AddField(e.ChannelFormat.Title, e.ChannelFormat.Values[s.ChannelFormat.UnwrapOr(-1).String()], true).
 		AddField(e.History.Title, e.History.Values[s.Privacy.UnwrapOr(-1).String()], true).
-		AddField(e.UsernameFormat.Title, e.UsernameFormat.Values[s.UsernameFormat.UnwrapOr(-1).String()], true)
+		AddField(e.UsernameFormat.Title, e.UsernameFormat.Values[s.UsernameFormat.UnwrapOr(-1).String()], true).
+		AddField(e.TimelineFormat.Title, e.TimelineFormat.Values[s.TimelineFormat.UnwrapOr(-1).String()], true).
+		AddField(e.TimelineTheme.Title, e.TimelineTheme.Values[s.TimelineTheme.UnwrapOr(-1).String()], true).
+		AddField(e.TimelineLegend.Title, e.TimelineLegend.Values[s.TimelineLegend.String()], true).
+		AddField(e.TimelineLabels.Title, e.TimelineLabels.Values[s.TimelineLabels.String()], true).
+		AddField(e.TimelineLimits.Title, s.timelineLimits(), true).
+		AddField(e.StageLiveOnly.Title, e.StageLiveOnly.Values[s.StageLiveOnly.String()], true).
+		AddField(e.ColorSource.Title, s.colorValue(), true).
+		AddField(e.Thumbnail.Title, s.thumbnailValue(), true).
+		AddField(e.Template.Title, s.templateValue(), false)
 
 	if status != "" {
 		builder.SetFooterText(status)
@@ -163,6 +294,72 @@ func (s *Rule) buildEmbed(status string) discord.Embed {
 	return builder.Build()
 }
 
+func (s *Rule) timelineLimits() string {
+	e := locale.Get(s.locale).Form.Settings.Fields.TimelineLimits
+	limits := make([]string, 0, 2)
+	if s.TimelineMaxMembers > 0 {
+		limits = append(limits, fmt.Sprintf(e.MaxMembers, s.TimelineMaxMembers))
+	}
+	if s.TimelineMaxSize > 0 {
+		limits = append(limits, fmt.Sprintf(e.MaxSize, s.TimelineMaxSize))
+	}
+	if len(limits) == 0 {
+		return e.NoLimit
+	}
+	return strings.Join(limits, ", ")
+}
+
+// templateName is either default or custom
+func (s *Rule) templateName() string {
+	e := locale.Get(s.locale).Form.Settings.Fields.Template
+	if s.Template.IsEmpty() {
+		return e.Values["default"]
+	}
+	return e.Values["custom"]
+}
+
+// templateValue shows the template with the variables available
+func (s *Rule) templateValue() string {
+	e := locale.Get(s.locale).Form.Settings.Fields.Template
+	variables := make([]string, len(rule.TemplateVariables))
+	for i, v := range rule.TemplateVariables {
+		variables[i] = "`{" + v + "}`"
+	}
+	return s.templateName() + "\n" + fmt.Sprintf(e.Variables, strings.Join(variables, " "))
+}
+
+// colorValue shows the source of the color, with the colors if fixed
+func (s *Rule) colorValue() string {
+	e := locale.Get(s.locale).Form.Settings.Fields.ColorSource
+	source := s.ColorSource.UnwrapOr(-1)
+	value := e.Values[source.String()]
+	switch {
+	case source == rule.ColorSourceFixed:
+		return value + "\n" + fmt.Sprintf(e.Colors, formatColor(s.OngoingColor), formatColor(s.EndedColor))
+	case source == rule.ColorSourceChannel && s.Scope != rule.ScopeChannel:
+		// the preview cannot show the color of every channel in the scope
+		return value + "\n" + e.Sample
+	default:
+		return value
+	}
+}
+
+// thumbnailValue shows the kind of the thumbnail, with the url if custom
+func (s *Rule) thumbnailValue() string {
+	e := locale.Get(s.locale).Form.Settings.Fields.Thumbnail
+	thumbnail := s.Thumbnail.UnwrapOr(-1)
+	value := e.Values[thumbnail.String()]
+	if thumbnail == rule.ThumbnailCustom && s.ThumbnailURL != "" {
+		return value + "\n" + s.ThumbnailURL
+	}
+	return value
+}
+
+// formatColor formats the color like #547443, none is the default color
+func formatColor(color extstd.Option[int]) string {
+	return fmt.Sprintf("#%06X", color.UnwrapOr(rule.DefaultColor))
+}
+
 func (s *Rule) title() string {
 	switch s.Scope {
 	case rule.ScopeGuild:
@@ -188,240 +385,569 @@ func (s *Rule) description() string {
 }
 
 func (s *Rule) buildComponents() []discord.ContainerComponent {
+	return s.router().Components()
+}
 
-	switch s.confirm {
-	case confirmSave:
-		return s.buildConfirmSaveComponents()
-	case confirmDelete:
-		return s.buildConfirmDeleteComponents()
-	}
-
+// router declares the components of the form.
+// the buttons to save the settings are shown on every page, and the settings are split into the pages.
+func (s *Rule) router() *form.Router {
 	f := locale.Get(s.locale).Form.Settings.Fields
+	b := locale.Get(s.locale).Form.Settings.Buttons
+	p := locale.Get(s.locale).Form.Settings.Pages
+	disabled := !bool(s.Enabled)
+
+	return form.NewRouter(s.update, &s.pending).
+		Add(
+			// enable/disable, save, discard, delete buttons
+			&form.Toggle{
+				ID:    settingKeyEnabled,
+				Value: &s.Enabled,
+				Label: func(value form.Bool) string {
+					return b.ToggleEnability[(!value).String()]
+				},
+				OnChange: func(value form.Bool) string {
+					return fmt.Sprintf(f.Notification.Update, f.Notification.Values[value.String()])
+				},
+			},
+			&form.Button{
+				ID:    settingButtonSave,
+				Label: b.Save.Primary,
+				Style: discord.ButtonStyleSuccess,
+				Guard: s.validate,
+				Confirm: &form.Confirm{
+					Status: b.Save.ConfirmStatus,
+					Label:  b.Save.Confirm,
+					Cancel: b.Save.Cancel,
+					Style:  discord.ButtonStyleSuccess,
+				},
+				Handler: s.save,
+			},
+			&form.Button{
+				ID:      settingButtonDiscard,
+				Label:   b.Discard,
+				Style:   discord.ButtonStyleSecondary,
+				Handler: s.discard,
+			},
+			&form.Button{
+				ID:     settingButtonDelete,
+				Label:  b.Delete.Primary,
+				Style:  discord.ButtonStyleDanger,
+				Hidden: !s.HasDeleteButton,
+				Confirm: &form.Confirm{
+					Status: b.Delete.ConfirmStatus,
+					Label:  b.Delete.Confirm,
+					Cancel: b.Delete.Cancel,
+					Style:  discord.ButtonStyleDanger,
+				},
+				Handler: s.delete,
+			},
+		).
+		Pages(&s.page,
+			form.Page{
+				ID:    settingPageDelivery,
+				Label: p.Delivery,
+				Fields: []form.Field{
+					&form.ChannelSelect{
+						ID:           settingKeyNotificationChannel,
+						Placeholder:  f.NotificationChannel.Title,
+						Value:        &s.NotificationChannel,
+						ChannelTypes: []discord.ChannelType{discord.ChannelTypeGuildText},
+						OnChange: func(event *events.ComponentInteractionCreate, channelID snowflake.ID) (string, error) {
+							channel, err := event.Client().Rest().GetChannel(channelID)
+							if err != nil {
+								return "", err
+							}
+							return fmt.Sprintf(f.NotificationChannel.Update, "#"+channel.Name()), nil
+						},
+						Disabled: disabled,
+					},
+					&form.EnumSelect[rule.ChannelFormat]{
+						ID:          settingKeyChannelFormat,
+						Placeholder: f.ChannelFormat.Title,
+						Value:       &s.ChannelFormat,
+						Options:     []rule.ChannelFormat{rule.ChannelFormatDisplay, rule.ChannelFormatMention},
+						Parse:       rule.ParseChannelFormat,
+						Label:       localized[rule.ChannelFormat](f.ChannelFormat.Values),
+						OnChange:    updated[rule.ChannelFormat](f.ChannelFormat.Update, f.ChannelFormat.Values),
+						Disabled:    disabled,
+					},
+				},
+			},
+			form.Page{
+				ID:    settingPageDisplay,
+				Label: p.Display,
+				Fields: []form.Field{
+					&form.EnumSelect[rule.History]{
+						ID:          settingKeyPrivacy,
+						Placeholder: f.History.Title,
+						Value:       &s.Privacy,
+						Options: []rule.History{
+							rule.HistoryNone,
+							rule.HistoryNameOnly,
+							rule.HistoryNameWithDuration,
+							rule.HistoryNameWithDurationAndTimeline,
+						},
+						Parse:    rule.ParseHistory,
+						Label:    localized[rule.History](f.History.Values),
+						OnChange: updated[rule.History](f.History.Update, f.History.Values),
+						Disabled: disabled,
+					},
+					&form.EnumSelect[rule.UserFormat]{
+						ID:          settingKeyUsernameFormat,
+						Placeholder: f.UsernameFormat.Title,
+						Value:       &s.UsernameFormat,
+						Options:     []rule.UserFormat{rule.UserFormatUsername, rule.UserFormatDisplay, rule.UserFormatMention},
+						Parse:       rule.ParseUserFormat,
+						Label:       localized[rule.UserFormat](f.UsernameFormat.Values),
+						OnChange:    updated[rule.UserFormat](f.UsernameFormat.Update, f.UsernameFormat.Values),
+						Disabled:    disabled || !s.Privacy.UnwrapOr(-1).ShouldDisplayName(),
+					},
+				},
+			},
+			form.Page{
+				ID:    settingPageTimeline,
+				Label: p.Timeline,
+				Fields: []form.Field{
+					&form.Toggle{
+						ID:       settingKeyTimelineLegend,
+						Value:    &s.TimelineLegend,
+						Label:    toggleLabel(f.TimelineLegend.Label, f.TimelineLegend.Values),
+						OnChange: updated[form.Bool](f.TimelineLegend.Update, f.TimelineLegend.Values),
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled,
+					},
+					&form.Toggle{
+						ID:       settingKeyTimelineLabels,
+						Value:    &s.TimelineLabels,
+						Label:    toggleLabel(f.TimelineLabels.Label, f.TimelineLabels.Values),
+						OnChange: updated[form.Bool](f.TimelineLabels.Update, f.TimelineLabels.Values),
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled,
+					},
+					&form.EnumSelect[rule.TimelineFormat]{
+						ID:          settingKeyTimelineFormat,
+						Placeholder: f.TimelineFormat.Title,
+						Value:       &s.TimelineFormat,
+						Options:     []rule.TimelineFormat{rule.TimelineFormatPNG, rule.TimelineFormatSVG, rule.TimelineFormatReplay},
+						Parse:       rule.ParseTimelineFormat,
+						Label:       localized[rule.TimelineFormat](f.TimelineFormat.Values),
+						OnChange:    updated[rule.TimelineFormat](f.TimelineFormat.Update, f.TimelineFormat.Values),
+						Disabled:    disabled,
+					},
+					&form.EnumSelect[rule.TimelineTheme]{
+						ID:          settingKeyTimelineTheme,
+						Placeholder: f.TimelineTheme.Title,
+						Value:       &s.TimelineTheme,
+						Options: []rule.TimelineTheme{
+							rule.TimelineThemeLight,
+							rule.TimelineThemeDark,
+							rule.TimelineThemeHighContrast,
+							rule.TimelineThemeColorblind,
+						},
+						Parse:    rule.ParseTimelineTheme,
+						Label:    localized[rule.TimelineTheme](f.TimelineTheme.Values),
+						OnChange: updated[rule.TimelineTheme](f.TimelineTheme.Update, f.TimelineTheme.Values),
+						Disabled: disabled,
+					},
+				},
+			},
+			form.Page{
+				ID:    settingPageFilters,
+				Label: p.Filters,
+				Fields: []form.Field{
+					&form.Toggle{
+						ID:       settingKeyStageLiveOnly,
+						Value:    &s.StageLiveOnly,
+						Label:    toggleLabel(f.StageLiveOnly.Label, f.StageLiveOnly.Values),
+						OnChange: updated[form.Bool](f.StageLiveOnly.Update, f.StageLiveOnly.Values),
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled,
+					},
+					&form.Button{
+						ID:       settingButtonTimelineLimits,
+						Label:    b.TimelineLimits,
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled,
+						Handler: func(event *events.ComponentInteractionCreate) error {
+							return event.Modal(s.buildTimelineLimitsModal())
+						},
+					},
+				},
+			},
+			form.Page{
+				ID:    settingPageTemplate,
+				Label: p.Template,
+				Fields: []form.Field{
+					&form.Button{
+						ID:       settingButtonTemplate,
+						Label:    b.Template,
+						Style:    discord.ButtonStylePrimary,
+						Disabled: disabled,
+						Handler: func(event *events.ComponentInteractionCreate) error {
+							return event.Modal(s.buildTemplateModal())
+						},
+					},
+					&form.Button{
+						ID:       settingButtonResetTemplate,
+						Label:    b.ResetTemplate,
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled || s.Template.IsEmpty(),
+						Handler: func(event *events.ComponentInteractionCreate) error {
+							s.Template = rule.Template{}
+							return event.UpdateMessage(s.update(f.Template.Reset))
+						},
+					},
+					&form.Button{
+						ID:       settingButtonBranding,
+						Label:    b.Branding,
+						Style:    discord.ButtonStyleSecondary,
+						Disabled: disabled,
+						Handler: func(event *events.ComponentInteractionCreate) error {
+							return event.Modal(s.buildBrandingModal())
+						},
+					},
+					&form.EnumSelect[rule.ColorSource]{
+						ID:          settingKeyColorSource,
+						Placeholder: f.ColorSource.Title,
+						Value:       &s.ColorSource,
+						Options:     []rule.ColorSource{rule.ColorSourceFixed, rule.ColorSourceChannel, rule.ColorSourceStarterRole},
+						Parse:       rule.ParseColorSource,
+						Label:       localized[rule.ColorSource](f.ColorSource.Values),
+						OnChange:    updated[rule.ColorSource](f.ColorSource.Update, f.ColorSource.Values),
+						Disabled:    disabled,
+					},
+					&form.EnumSelect[rule.Thumbnail]{
+						ID:          settingKeyThumbnail,
+						Placeholder: f.Thumbnail.Title,
+						Value:       &s.Thumbnail,
+						Options: []rule.Thumbnail{
+							rule.ThumbnailNone,
+							rule.ThumbnailGuildIcon,
+							rule.ThumbnailStarterAvatar,
+							rule.ThumbnailCustom,
+						},
+						Parse:    rule.ParseThumbnail,
+						Label:    localized[rule.Thumbnail](f.Thumbnail.Values),
+						OnChange: updated[rule.Thumbnail](f.Thumbnail.Update, f.Thumbnail.Values),
+						Disabled: disabled,
+					},
+				},
+			},
+		)
+}
 
-	channel := discord.NewChannelSelectMenu(settingKeyNotificationChannel, f.NotificationChannel.Title).
-		WithChannelTypes(discord.ChannelTypeGuildText).
-		WithMaxValues(1).
-		WithMinValues(1)
-	if s.NotificationChannel.IsSome() {
-		channel = channel.AddDefaultValue(s.NotificationChannel.Unwrap())
+// toggleLabel returns the label of the toggle showing the current value
+func toggleLabel(format string, values map[string]string) func(value form.Bool) string {
+	return func(value form.Bool) string {
+		return fmt.Sprintf(format, values[value.String()])
 	}
+}
 
-	localizedOption := func(values map[string]string) func(key string) discord.StringSelectMenuOption {
-		return func(key string) discord.StringSelectMenuOption {
-			return discord.NewStringSelectMenuOption(values[key], key)
-		}
+// localized returns the label of the value from the localized values
+func localized[T form.Enum](values map[string]string) func(value T) string {
+	return func(value T) string {
+		return values[value.String()]
 	}
+}
 
-	markAsDefault := func(value string) func(option discord.StringSelectMenuOption) discord.StringSelectMenuOption {
-		return func(option discord.StringSelectMenuOption) discord.StringSelectMenuOption {
-			if option.Value == value {
-				return option.WithDefault(true)
-			}
-			return option
-		}
+// updated returns the status after the value is changed
+func updated[T form.Enum](format string, values map[string]string) func(value T) string {
+	return func(value T) string {
+		return fmt.Sprintf(format, values[value.String()])
 	}
+}
 
-	markAsDefaultPrivacy := markAsDefault(s.Privacy.UnwrapOr(-1).String())
-	privacyLocalizedOption := localizedOption(f.History.Values)
-	history := discord.
-		NewStringSelectMenu(
-			settingKeyPrivacy, f.History.Title,
-			markAsDefaultPrivacy(privacyLocalizedOption(rule.HistoryNone.String())),
-			markAsDefaultPrivacy(privacyLocalizedOption(rule.HistoryNameOnly.String())),
-			markAsDefaultPrivacy(privacyLocalizedOption(rule.HistoryNameWithDuration.String())),
-			markAsDefaultPrivacy(privacyLocalizedOption(rule.HistoryNameWithDurationAndTimeline.String())),
-		).
-		WithMinValues(1).
-		WithMaxValues(1)
-
-	markAsDefaultUsernameFormat := markAsDefault(s.UsernameFormat.UnwrapOr(-1).String())
-	usernameLocalizedOption := localizedOption(f.UsernameFormat.Values)
-
-	usernameFormat := discord.
-		NewStringSelectMenu(
-			settingKeyUsernameFormat, f.UsernameFormat.Title,
-			markAsDefaultUsernameFormat(usernameLocalizedOption(rule.UserFormatUsername.String())),
-			markAsDefaultUsernameFormat(usernameLocalizedOption(rule.UserFormatDisplay.String())),
-			markAsDefaultUsernameFormat(usernameLocalizedOption(rule.UserFormatMention.String())),
-		).
-		WithMinValues(1).
-		WithMaxValues(1)
-
-	if !s.Privacy.UnwrapOr(-1).ShouldDisplayName() {
-		usernameFormat = usernameFormat.AsDisabled()
+// Authorize allows only the owner to change the settings
+func (s *Rule) Authorize(user discord.User) error {
+	if s.owner != user.ID {
+		return errors.New(locale.Get(s.locale).Form.Settings.Error.NotOwner)
 	}
+	return nil
+}
 
-	markAsDefaultChannelFormat := markAsDefault(s.ChannelFormat.UnwrapOr(-1).String())
-	channelFormatLocalizedOption := localizedOption(f.ChannelFormat.Values)
+// IsFinished is true once the settings are saved, discarded or deleted
+func (s *Rule) IsFinished() bool {
+	return s.Finalized
+}
 
-	channelFormat := discord.
-		NewStringSelectMenu(
-			settingKeyChannelFormat, f.ChannelFormat.Title,
-			markAsDefaultChannelFormat(channelFormatLocalizedOption(rule.ChannelFormatDisplay.String())),
-			markAsDefaultChannelFormat(channelFormatLocalizedOption(rule.ChannelFormatMention.String())),
-		).
-		WithMinValues(1).
-		WithMaxValues(1)
+func (s *Rule) Handle(event *events.ComponentInteractionCreate) error {
+	_, err := s.router().Handle(event)
+	return err
+}
 
-	if !s.Enabled {
-		channel = channel.AsDisabled()
-		history = history.AsDisabled()
-		usernameFormat = usernameFormat.AsDisabled()
-		channelFormat = channelFormat.AsDisabled()
+func (s *Rule) save(event *events.ComponentInteractionCreate) error {
+	s.Finalized = true
+
+	r := rule.Rule{}
+
+	if s.Enabled {
+		r = s.base
+		r.Enabled = true
+		r.NotificationChannel = s.NotificationChannel.Unwrap()
+		r.ChannelFormat = s.ChannelFormat.Unwrap()
+		r.History = s.Privacy.Unwrap()
+		r.UserFormat = s.UsernameFormat.Unwrap()
+		r.TimelineFormat = s.TimelineFormat.Unwrap()
+		r.TimelineTheme = s.TimelineTheme.Unwrap()
+		r.TimelineLegend = bool(s.TimelineLegend)
+		r.TimelineLabels = bool(s.TimelineLabels)
+		r.TimelineMaxMembers = s.TimelineMaxMembers
+		r.TimelineMaxSize = s.TimelineMaxSize
+		r.StageLiveOnly = bool(s.StageLiveOnly)
+		r.Template = s.Template
+		r.ColorSource = s.ColorSource.UnwrapOr(rule.ColorSourceFixed)
+		r.OngoingColor = toPtr(s.OngoingColor)
+		r.EndedColor = toPtr(s.EndedColor)
+		r.Thumbnail = s.Thumbnail.UnwrapOr(rule.ThumbnailNone)
+		r.ThumbnailURL = s.ThumbnailURL
 	}
 
-	menus := []discord.ContainerComponent{
-		discord.NewActionRow(channel),
-		discord.NewActionRow(channelFormat),
-		discord.NewActionRow(history),
-		discord.NewActionRow(usernameFormat),
-	}
+	s.ruleManager.SaveRule(
+		s.Scope,
+		s.ScopeIdentifier,
+		r,
+	)
+	return event.UpdateMessage(s.update(locale.Get(s.locale).Form.Settings.Validate.Success))
+}
 
-	// enable/disable, save, discard, delete buttons
-	b := locale.Get(s.locale).Form.Settings.Buttons
+func (s *Rule) discard(event *events.ComponentInteractionCreate) error {
+	s.Finalized = true
+	return event.UpdateMessage(discord.NewMessageUpdateBuilder().SetContent("discard").SetEmbeds().SetContainerComponents().Build())
+}
 
-	toggle := discord.NewPrimaryButton(b.ToggleEnability[(!s.Enabled).String()], settingKeyEnabled)
-	save := discord.NewSuccessButton(b.Save.Primary, settingButtonSave)
-	discard := discord.NewSecondaryButton(b.Discard, settingButtonDiscard)
-	delete := discord.NewDangerButton(b.Delete.Primary, settingButtonDelete)
+func (s *Rule) delete(event *events.ComponentInteractionCreate) error {
+	s.Finalized = true
+	s.ruleManager.DeleteRule(s.Scope, s.ScopeIdentifier)
+	return event.UpdateMessage(discord.NewMessageUpdateBuilder().SetContent("deleted").SetEmbeds().SetContainerComponents().Build())
+}
 
-	buttonRow := discord.NewActionRow().
-		AddComponents(toggle, save, discard)
+func (s *Rule) buildTimelineLimitsModal() discord.ModalCreate {
+	m := locale.Get(s.locale).Form.Settings.Modals.TimelineLimits
 
-	if s.HasDeleteButton {
-		buttonRow = buttonRow.AddComponents(delete)
+	limit := func(value int) string {
+		if value == 0 {
+			return ""
+		}
+		return strconv.Itoa(value)
 	}
 
-	return append([]discord.ContainerComponent{
-		buttonRow,
-	}, menus...)
+	return discord.NewModalCreateBuilder().
+		SetCustomID(settingModalTimelineLimits).
+		SetTitle(m.Title).
+		AddActionRow(discord.NewShortTextInput(settingKeyMaxMembers, m.MaxMembers.Label).
+			WithPlaceholder(m.MaxMembers.Placeholder).
+			WithValue(limit(s.TimelineMaxMembers)).
+			WithRequired(false)).
+		AddActionRow(discord.NewShortTextInput(settingKeyMaxSize, m.MaxSize.Label).
+			WithPlaceholder(m.MaxSize.Placeholder).
+			WithValue(limit(s.TimelineMaxSize)).
+			WithRequired(false)).
+		Build()
+}
 
+func (s *Rule) HandleModal(event *events.ModalSubmitInteractionCreate) error {
+	switch event.Data.CustomID {
+	case settingModalTimelineLimits:
+		return s.handleTimelineLimitsModal(event)
+	case settingModalTemplate:
+		return s.handleTemplateModal(event)
+	case settingModalBranding:
+		return s.handleBrandingModal(event)
+	}
+	return nil
 }
 
-func (s *Rule) buildConfirmSaveComponents() []discord.ContainerComponent {
-	b := locale.Get(s.locale).Form.Settings.Buttons.Save
+func (s *Rule) handleTimelineLimitsModal(event *events.ModalSubmitInteractionCreate) error {
+	v := locale.Get(s.locale).Form.Settings.Validate.Error
+	messages := []string{}
+
+	members, ok := parseLimit(event.Data.Text(settingKeyMaxMembers), 1, maxTimelineMembers)
+	if !ok {
+		messages = append(messages, fmt.Sprintf(v.InvalidMaxMembers, maxTimelineMembers))
+	}
 
-	confirm := discord.NewSuccessButton(b.Confirm, settingButtonConfirmSave)
-	cancel := discord.NewSecondaryButton(b.Cancel, settingButtonCancel)
+	size, ok := parseLimit(event.Data.Text(settingKeyMaxSize), minTimelineSize, maxTimelineSize)
+	if !ok {
+		messages = append(messages, fmt.Sprintf(v.InvalidMaxSize, minTimelineSize, maxTimelineSize))
+	}
 
-	return []discord.ContainerComponent{
-		discord.NewActionRow(confirm, cancel),
+	// the invalid values are shown in the form, and the previous values are kept
+	if len(messages) > 0 {
+		return event.UpdateMessage(s.update(strings.Join(messages, "\n")))
 	}
+
+	s.TimelineMaxMembers = members
+	s.TimelineMaxSize = size
+
+	e := locale.Get(s.locale).Form.Settings.Fields.TimelineLimits
+	return event.UpdateMessage(s.update(fmt.Sprintf(e.Update, s.timelineLimits())))
 }
 
-func (s *Rule) buildConfirmDeleteComponents() []discord.ContainerComponent {
-	b := locale.Get(s.locale).Form.Settings.Buttons.Delete
+func (s *Rule) buildTemplateModal() discord.ModalCreate {
+	m := locale.Get(s.locale).Form.Settings.Modals.Template
+
+	return discord.NewModalCreateBuilder().
+		SetCustomID(settingModalTemplate).
+		SetTitle(m.Title).
+		AddActionRow(discord.NewShortTextInput(settingKeyTemplateTitle, m.EmbedTitle.Label).
+			WithPlaceholder(m.EmbedTitle.Placeholder).
+			WithValue(s.Template.Title).
+			WithMaxLength(maxTemplateTitle).
+			WithRequired(false)).
+		AddActionRow(discord.NewParagraphTextInput(settingKeyTemplateDescription, m.EmbedDescription.Label).
+			WithPlaceholder(m.EmbedDescription.Placeholder).
+			WithValue(s.Template.Description).
+			WithMaxLength(maxTemplateDescription).
+			WithRequired(false)).
+		AddActionRow(discord.NewShortTextInput(settingKeyTemplateColor, m.EmbedColor.Label).
+			WithPlaceholder(m.EmbedColor.Placeholder).
+			WithValue(s.Template.Color).
+			WithMaxLength(maxTemplateColor).
+			WithRequired(false)).
+		AddActionRow(discord.NewParagraphTextInput(settingKeyTemplateFooter, m.EmbedFooter.Label).
+			WithPlaceholder(m.EmbedFooter.Placeholder).
+			WithValue(s.Template.Footer).
+			WithMaxLength(maxTemplateFooter).
+			WithRequired(false)).
+		Build()
+}
 
-	confirm := discord.NewDangerButton(b.Confirm, settingButtonConfirmDelete)
-	cancel := discord.NewSecondaryButton(b.Cancel, settingButtonCancel)
+func (s *Rule) handleTemplateModal(event *events.ModalSubmitInteractionCreate) error {
+	template := rule.Template{
+		Title:       strings.TrimSpace(event.Data.Text(settingKeyTemplateTitle)),
+		Description: strings.TrimSpace(event.Data.Text(settingKeyTemplateDescription)),
+		Color:       strings.TrimSpace(event.Data.Text(settingKeyTemplateColor)),
+		Footer:      strings.TrimSpace(event.Data.Text(settingKeyTemplateFooter)),
+	}
 
-	return []discord.ContainerComponent{
-		discord.NewActionRow(confirm, cancel),
+	// the invalid template is not applied, the previous one is kept
+	if err := template.Validate(); err != nil {
+		return event.UpdateMessage(s.update(s.templateError(err)))
 	}
+
+	s.Template = template
+
+	e := locale.Get(s.locale).Form.Settings.Fields.Template
+	return event.UpdateMessage(s.update(fmt.Sprintf(e.Update, s.templateName())))
 }
 
-func (s *Rule) Handle(event *events.ComponentInteractionCreate) error {
+// templateError describes which field of the template is invalid
+func (s *Rule) templateError(err error) string {
+	m := locale.Get(s.locale).Form.Settings.Modals.Template
+	v := locale.Get(s.locale).Form.Settings.Validate.Error
 
-	if s.owner != event.User().ID {
-		event.CreateMessage(discord.NewMessageCreateBuilder().
-			SetContent(locale.Get(s.locale).Form.Settings.Error.NotOwner).
-			SetEphemeral(true).
-			Build(),
-		)
+	var templateErr *rule.TemplateError
+	if !errors.As(err, &templateErr) {
+		return fmt.Sprintf(v.InvalidTemplate, m.Title, err)
 	}
 
-	e := locale.Get(s.locale).Form.Settings.Fields
-
-	switch event.Data.CustomID() {
+	label := map[rule.TemplateField]string{
+		rule.TemplateFieldTitle:       m.EmbedTitle.Label,
+		rule.TemplateFieldDescription: m.EmbedDescription.Label,
+		rule.TemplateFieldColor:       m.EmbedColor.Label,
+		rule.TemplateFieldFooter:      m.EmbedFooter.Label,
+	}[templateErr.Field]
+	return fmt.Sprintf(v.InvalidTemplate, label, templateErr.Err)
+}
 
-	case settingKeyEnabled:
-		s.Enabled = !s.Enabled
-		if err := event.UpdateMessage(s.update(fmt.Sprintf(e.Notification.Update, e.Notification.Values[s.Enabled.String()]))); err != nil {
-			return err
-		}
+func (s *Rule) buildBrandingModal() discord.ModalCreate {
+	m := locale.Get(s.locale).Form.Settings.Modals.Branding
 
-	case settingKeyNotificationChannel:
-		value := event.ChannelSelectMenuInteractionData().Values[0]
-		s.NotificationChannel = extstd.Some(value)
-		channel, err := event.Client().Rest().GetChannel(value)
-		if err != nil {
-			return err
-		}
-		if err := event.UpdateMessage(s.update(fmt.Sprintf(e.NotificationChannel.Update, "#"+channel.Name()))); err != nil {
-			return err
+	color := func(value extstd.Option[int]) string {
+		if value.IsNone() {
+			return ""
 		}
+		return formatColor(value)
+	}
 
-	case settingKeyUsernameFormat:
-		value := event.StringSelectMenuInteractionData().Values[0]
-		s.UsernameFormat = extstd.Some(rule.ParseUserFormat(value))
-		if err := event.UpdateMessage(s.update(fmt.Sprintf(e.UsernameFormat.Update, e.UsernameFormat.Values[value]))); err != nil {
-			return err
-		}
+	return discord.NewModalCreateBuilder().
+		SetCustomID(settingModalBranding).
+		SetTitle(m.Title).
+		AddActionRow(discord.NewShortTextInput(settingKeyOngoingColor, m.OngoingColor.Label).
+			WithPlaceholder(m.OngoingColor.Placeholder).
+			WithValue(color(s.OngoingColor)).
+			WithRequired(false)).
+		AddActionRow(discord.NewShortTextInput(settingKeyEndedColor, m.EndedColor.Label).
+			WithPlaceholder(m.EndedColor.Placeholder).
+			WithValue(color(s.EndedColor)).
+			WithRequired(false)).
+		AddActionRow(discord.NewShortTextInput(settingKeyThumbnailURL, m.ThumbnailURL.Label).
+			WithPlaceholder(m.ThumbnailURL.Placeholder).
+			WithValue(s.ThumbnailURL).
+			WithMaxLength(maxThumbnailURL).
+			WithRequired(false)).
+		Build()
+}
 
-	case settingKeyChannelFormat:
-		value := event.StringSelectMenuInteractionData().Values[0]
-		s.ChannelFormat = extstd.Some(rule.ParseChannelFormat(value))
-		if err := event.UpdateMessage(s.update(fmt.Sprintf(e.ChannelFormat.Update, e.ChannelFormat.Values[value]))); err != nil {
-			return err
-		}
+func (s *Rule) handleBrandingModal(event *events.ModalSubmitInteractionCreate) error {
+	m := locale.Get(s.locale).Form.Settings.Modals.Branding
+	v := locale.Get(s.locale).Form.Settings.Validate.Error
+	messages := []string{}
 
-	case settingKeyPrivacy:
-		value := event.StringSelectMenuInteractionData().Values[0]
-		s.Privacy = extstd.Some(rule.ParseHistory(value))
-		if err := event.UpdateMessage(s.update(fmt.Sprintf(e.History.Update, e.History.Values[value]))); err != nil {
-			return err
-		}
+	ongoing, ok := parseColor(event.Data.Text(settingKeyOngoingColor))
+	if !ok {
+		messages = append(messages, fmt.Sprintf(v.InvalidColor, m.OngoingColor.Label))
+	}
 
-		// button interaction
-	case settingButtonSave:
-		if err := s.validate(); err != nil {
-			return event.UpdateMessage(s.update(err.Error()))
-		}
-		s.confirm = confirmSave
-		return event.UpdateMessage(s.update(locale.Get(s.locale).Form.Settings.Buttons.Save.ConfirmStatus))
-	case settingButtonConfirmSave:
-		if err := s.validate(); err != nil {
-			return event.UpdateMessage(s.update(err.Error()))
-		}
+	ended, ok := parseColor(event.Data.Text(settingKeyEndedColor))
+	if !ok {
+		messages = append(messages, fmt.Sprintf(v.InvalidColor, m.EndedColor.Label))
+	}
 
-		s.Finalized = true
-		s.confirm = confirmNone
+	thumbnailURL := strings.TrimSpace(event.Data.Text(settingKeyThumbnailURL))
+	if thumbnailURL != "" && !isImageURL(thumbnailURL) {
+		messages = append(messages, v.InvalidThumbnailURL)
+	}
 
-		r := rule.Rule{}
+	// same as the timeline limits, the previous values are kept if any is invalid
+	if len(messages) > 0 {
+		return event.UpdateMessage(s.update(strings.Join(messages, "\n")))
+	}
 
-		if s.Enabled {
-			r = rule.Rule{
-				Enabled:             true,
-				NotificationChannel: s.NotificationChannel.Unwrap(),
-				ChannelFormat:       s.ChannelFormat.Unwrap(),
-				History:             s.Privacy.Unwrap(),
-				UserFormat:          s.UsernameFormat.Unwrap(),
-			}
-		}
+	s.OngoingColor = ongoing
+	s.EndedColor = ended
+	s.ThumbnailURL = thumbnailURL
+	return event.UpdateMessage(s.update(m.Update))
+}
 
-		s.ruleManager.SaveRule(
-			s.Scope,
-			s.ScopeIdentifier,
-			r,
-		)
-		return event.UpdateMessage(s.update(locale.Get(s.locale).Form.Settings.Validate.Success))
+// parseColor parses the color which is empty for the default color
+func parseColor(text string) (extstd.Option[int], bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return extstd.None[int](), true
+	}
 
-	case settingButtonDiscard:
-		return event.UpdateMessage(discord.NewMessageUpdateBuilder().SetContent("discard").SetEmbeds().SetContainerComponents().Build())
+	color, err := rule.ParseColor(text)
+	if err != nil {
+		return extstd.None[int](), false
+	}
+	return extstd.Some(color), true
+}
 
-	case settingButtonDelete:
-		s.confirm = confirmDelete
-		return event.UpdateMessage(s.update(locale.Get(s.locale).Form.Settings.Buttons.Delete.ConfirmStatus))
-	case settingButtonConfirmDelete:
-		s.ruleManager.DeleteRule(s.Scope, s.ScopeIdentifier)
-		return event.UpdateMessage(discord.NewMessageUpdateBuilder().SetContent("deleted").SetEmbeds().SetContainerComponents().Build())
+// isImageURL reports whether discord can show the url as the thumbnail
+func isImageURL(text string) bool {
+	u, err := url.Parse(text)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
 
-	case settingButtonCancel:
-		s.confirm = confirmNone
-		return event.UpdateMessage(s.update(""))
+// parseLimit parses the limit which is empty or zero for no limit, or between min and max
+func parseLimit(text string, min, max int) (int, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, true
 	}
 
-	return nil
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, false
+	}
+	if value != 0 && (value < min || value > max) {
+		return 0, false
+	}
+	return value, true
 }
 
 func (s *Rule) validate() error {
@@ -448,6 +974,14 @@ func (s *Rule) validate() error {
 		}
 	}
 
+	if err := s.Template.Validate(); err != nil {
+		messages = append(messages, s.templateError(err))
+	}
+
+	if s.Thumbnail.UnwrapOr(rule.ThumbnailNone) == rule.ThumbnailCustom && s.ThumbnailURL == "" {
+		messages = append(messages, locale.Get(s.locale).Form.Settings.Validate.Error.NoThumbnailURL)
+	}
+
 	if len(messages) > 0 {
 		return errors.New(strings.Join(messages, "\n"))
 	}
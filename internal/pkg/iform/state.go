@@ -0,0 +1,159 @@
+package iform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/internal/pkg/call"
+	"github.com/makeitchaccha/ringring/internal/pkg/rule"
+	"github.com/makeitchaccha/ringring/pkg/extstd"
+	"github.com/makeitchaccha/ringring/pkg/form"
+)
+
+var _ form.Persistent = (*Rule)(nil)
+
+const KindRule = "rule"
+
+// ruleState is the serialized state of the rule form.
+// options are stored as pointers, nil means none.
+type ruleState struct {
+	Owner           snowflake.ID   `json:"owner"`
+	Locale          discord.Locale `json:"locale"`
+	HasDeleteButton bool           `json:"has_delete_button"`
+	Finalized       bool           `json:"finalized"`
+	Pending         string         `json:"pending,omitempty"`
+	Page            string         `json:"page,omitempty"`
+	Base            rule.Rule      `json:"base"`
+
+	Scope           rule.Scope   `json:"scope"`
+	ScopeIdentifier snowflake.ID `json:"scope_identifier"`
+
+	Enabled             bool                `json:"enabled"`
+	NotificationChannel *snowflake.ID       `json:"notification_channel,omitempty"`
+	ChannelFormat       *rule.ChannelFormat `json:"channel_format,omitempty"`
+	Privacy             *rule.History       `json:"privacy,omitempty"`
+	UsernameFormat      *rule.UserFormat    `json:"username_format,omitempty"`
+
+	TimelineFormat     *rule.TimelineFormat `json:"timeline_format,omitempty"`
+	TimelineTheme      *rule.TimelineTheme  `json:"timeline_theme,omitempty"`
+	TimelineLegend     bool                 `json:"timeline_legend"`
+	TimelineLabels     bool                 `json:"timeline_labels"`
+	TimelineMaxMembers int                  `json:"timeline_max_members"`
+	TimelineMaxSize    int                  `json:"timeline_max_size"`
+	StageLiveOnly      bool                 `json:"stage_live_only"`
+
+	Template rule.Template `json:"template"`
+
+	ColorSource  *rule.ColorSource `json:"color_source,omitempty"`
+	OngoingColor *int              `json:"ongoing_color,omitempty"`
+	EndedColor   *int              `json:"ended_color,omitempty"`
+	Thumbnail    *rule.Thumbnail   `json:"thumbnail,omitempty"`
+	ThumbnailURL string            `json:"thumbnail_url,omitempty"`
+	Branding     call.Branding     `json:"branding"`
+}
+
+func (s *Rule) Kind() string {
+	return KindRule
+}
+
+func (s *Rule) Marshal() ([]byte, error) {
+	return json.Marshal(ruleState{
+		Owner:           s.owner,
+		Locale:          s.locale,
+		HasDeleteButton: s.HasDeleteButton,
+		Finalized:       s.Finalized,
+		Pending:         s.pending,
+		Page:            s.page,
+		Base:            s.base,
+
+		Scope:           s.Scope,
+		ScopeIdentifier: s.ScopeIdentifier,
+
+		Enabled:             bool(s.Enabled),
+		NotificationChannel: toPtr(s.NotificationChannel),
+		ChannelFormat:       toPtr(s.ChannelFormat),
+		Privacy:             toPtr(s.Privacy),
+		UsernameFormat:      toPtr(s.UsernameFormat),
+
+		TimelineFormat:     toPtr(s.TimelineFormat),
+		TimelineTheme:      toPtr(s.TimelineTheme),
+		TimelineLegend:     bool(s.TimelineLegend),
+		TimelineLabels:     bool(s.TimelineLabels),
+		TimelineMaxMembers: s.TimelineMaxMembers,
+		TimelineMaxSize:    s.TimelineMaxSize,
+		StageLiveOnly:      bool(s.StageLiveOnly),
+
+		Template: s.Template,
+
+		ColorSource:  toPtr(s.ColorSource),
+		OngoingColor: toPtr(s.OngoingColor),
+		EndedColor:   toPtr(s.EndedColor),
+		Thumbnail:    toPtr(s.Thumbnail),
+		ThumbnailURL: s.ThumbnailURL,
+		Branding:     s.Branding,
+	})
+}
+
+// RuleFactory restores the rule forms with the repository.
+func RuleFactory(ruleManager rule.Repository) form.Factory {
+	return func(data []byte) (form.Form, error) {
+		var state ruleState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rule form: %w", err)
+		}
+
+		return &Rule{
+			owner:           state.Owner,
+			ruleManager:     ruleManager,
+			locale:          state.Locale,
+			HasDeleteButton: state.HasDeleteButton,
+			Finalized:       state.Finalized,
+			pending:         state.Pending,
+			page:            state.Page,
+			base:            state.Base,
+
+			Scope:           state.Scope,
+			ScopeIdentifier: state.ScopeIdentifier,
+
+			Enabled:             form.Bool(state.Enabled),
+			NotificationChannel: fromPtr(state.NotificationChannel),
+			ChannelFormat:       fromPtr(state.ChannelFormat),
+			Privacy:             fromPtr(state.Privacy),
+			UsernameFormat:      fromPtr(state.UsernameFormat),
+
+			TimelineFormat:     fromPtr(state.TimelineFormat),
+			TimelineTheme:      fromPtr(state.TimelineTheme),
+			TimelineLegend:     form.Bool(state.TimelineLegend),
+			TimelineLabels:     form.Bool(state.TimelineLabels),
+			TimelineMaxMembers: state.TimelineMaxMembers,
+			TimelineMaxSize:    state.TimelineMaxSize,
+			StageLiveOnly:      form.Bool(state.StageLiveOnly),
+
+			Template: state.Template,
+
+			ColorSource:  fromPtr(state.ColorSource),
+			OngoingColor: fromPtr(state.OngoingColor),
+			EndedColor:   fromPtr(state.EndedColor),
+			Thumbnail:    fromPtr(state.Thumbnail),
+			ThumbnailURL: state.ThumbnailURL,
+			Branding:     state.Branding,
+		}, nil
+	}
+}
+
+func toPtr[T any](o extstd.Option[T]) *T {
+	if o.IsNone() {
+		return nil
+	}
+	v := o.Unwrap()
+	return &v
+}
+
+func fromPtr[T any](v *T) extstd.Option[T] {
+	if v == nil {
+		return extstd.None[T]()
+	}
+	return extstd.Some(*v)
+}
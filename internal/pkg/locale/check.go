@@ -0,0 +1,251 @@
+package locale
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"gopkg.in/yaml.v3"
+)
+
+type ProblemKind int
+
+const (
+	// ProblemMissing is a key which the locale does not have, the value of the fallback is used
+	ProblemMissing ProblemKind = iota
+	// ProblemExtra is a key which is never used
+	ProblemExtra
+	// ProblemPlaceholder is a value whose placeholders or arguments differ from the source
+	ProblemPlaceholder
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case ProblemMissing:
+		return "missing"
+	case ProblemExtra:
+		return "extra"
+	case ProblemPlaceholder:
+		return "placeholder"
+	default:
+		return "unknown"
+	}
+}
+
+// Problem is found in a locale file by Check
+type Problem struct {
+	Locale discord.Locale
+	Key    string
+	Kind   ProblemKind
+	Detail string
+}
+
+func (p Problem) String() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%s: %s: %s", string(p.Locale), p.Key, p.Kind)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", string(p.Locale), p.Key, p.Kind, p.Detail)
+}
+
+// Check reports the problems of every locale file in the directory.
+// the default locale is checked against Entry, and the others against the default locale,
+// including the placeholders like %[1]s which must be the same as the default locale.
+func Check(dir string) ([]Problem, error) {
+	files, err := localeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultFile, ok := files[DefaultLocale]
+	if !ok {
+		return nil, fmt.Errorf("default locale %s is not found", DefaultLocale)
+	}
+	source, err := flatten(defaultFile)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := checkSchema(source)
+
+	for locale, file := range files {
+		if locale == DefaultLocale {
+			continue
+		}
+		values, err := flatten(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range source {
+			translated, ok := values[key]
+			if !ok {
+				problems = append(problems, Problem{Locale: locale, Key: key, Kind: ProblemMissing})
+				continue
+			}
+			if expected, actual := placeholders(value), placeholders(translated); expected != actual {
+				problems = append(problems, Problem{
+					Locale: locale,
+					Key:    key,
+					Kind:   ProblemPlaceholder,
+					Detail: fmt.Sprintf("expected %q, got %q", expected, actual),
+				})
+			}
+		}
+		for key := range values {
+			if _, ok := source[key]; !ok {
+				problems = append(problems, Problem{Locale: locale, Key: key, Kind: ProblemExtra})
+			}
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Locale != problems[j].Locale {
+			return problems[i].Locale < problems[j].Locale
+		}
+		return problems[i].Key < problems[j].Key
+	})
+	return problems, nil
+}
+
+// checkSchema compares the keys of the default locale with the fields of Entry.
+// the keys of the maps, like the values of the enums, are not known by Entry.
+func checkSchema(source map[string]string) []Problem {
+	fields := make(map[string]bool)
+	maps := make(map[string]bool)
+	schemaKeys(reflect.TypeOf(Entry{}), "", fields, maps)
+
+	problems := make([]Problem, 0)
+	for key := range source {
+		parent := key
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			parent = key[:i]
+		}
+		if !fields[key] && !maps[parent] {
+			problems = append(problems, Problem{Locale: DefaultLocale, Key: key, Kind: ProblemExtra})
+		}
+	}
+	for key := range fields {
+		if _, ok := source[key]; !ok {
+			problems = append(problems, Problem{Locale: DefaultLocale, Key: key, Kind: ProblemMissing})
+		}
+	}
+	for key := range maps {
+		if !hasPrefix(source, key+".") {
+			problems = append(problems, Problem{Locale: DefaultLocale, Key: key, Kind: ProblemMissing})
+		}
+	}
+	return problems
+}
+
+func schemaKeys(t reflect.Type, prefix string, fields, maps map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			// same as the default of yaml
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			schemaKeys(field.Type, key, fields, maps)
+		case reflect.Map:
+			maps[key] = true
+		default:
+			fields[key] = true
+		}
+	}
+}
+
+func hasPrefix(values map[string]string, prefix string) bool {
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten reads the locale file into the values keyed by the dotted path, e.g. form.expired
+func flatten(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode yaml %s: %w", file, err)
+	}
+
+	values := make(map[string]string)
+	if len(root.Content) > 0 {
+		flattenNode(root.Content[0], "", values)
+	}
+	return values, nil
+}
+
+func flattenNode(node *yaml.Node, prefix string, values map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		values[prefix] = node.Value
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenNode(node.Content[i+1], key, values)
+	}
+}
+
+var placeholderPattern = regexp.MustCompile(`%(\[\d+\])?[a-zA-Z]`)
+
+// placeholders returns the distinct placeholders and the arguments of the message in the value, sorted
+func placeholders(value string) string {
+	found := placeholderPattern.FindAllString(strings.ReplaceAll(value, "%%", ""), -1)
+	if m, err := Compile(value); err != nil {
+		found = append(found, "(invalid message)")
+	} else {
+		for _, name := range m.Args() {
+			found = append(found, "{"+name+"}")
+		}
+	}
+	sort.Strings(found)
+
+	distinct := make([]string, 0, len(found))
+	for i, p := range found {
+		if i == 0 || found[i-1] != p {
+			distinct = append(distinct, p)
+		}
+	}
+	return strings.Join(distinct, " ")
+}
+
+// localeFiles finds the locale files in the directory, keyed by the locale
+func localeFiles(dir string) (map[discord.Locale]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	files := make(map[discord.Locale]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name, _ := strings.CutSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		files[discord.Locale(name)] = filepath.Join(dir, entry.Name())
+	}
+	return files, nil
+}
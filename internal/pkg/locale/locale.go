@@ -3,8 +3,6 @@ package locale
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/disgoorg/disgo/discord"
 	"gopkg.in/yaml.v3"
@@ -54,7 +52,95 @@ type Entry struct {
 					Update string            `yaml:"update"`
 					Values map[string]string `yaml:"values"`
 				} `yaml:"username-format"`
+				TimelineLimits struct {
+					Title      string `yaml:"title"`
+					Update     string `yaml:"update"`
+					MaxMembers string `yaml:"max-members"`
+					MaxSize    string `yaml:"max-size"`
+					NoLimit    string `yaml:"no-limit"`
+				} `yaml:"timeline-limits"`
+				TimelineFormat struct {
+					Title  string            `yaml:"title"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"timeline-format"`
+				TimelineTheme struct {
+					Title  string            `yaml:"title"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"timeline-theme"`
+				TimelineLegend struct {
+					Title  string            `yaml:"title"`
+					Label  string            `yaml:"label"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"timeline-legend"`
+				TimelineLabels struct {
+					Title  string            `yaml:"title"`
+					Label  string            `yaml:"label"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"timeline-labels"`
+				StageLiveOnly struct {
+					Title  string            `yaml:"title"`
+					Label  string            `yaml:"label"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"stage-live-only"`
+				Template struct {
+					Title     string            `yaml:"title"`
+					Update    string            `yaml:"update"`
+					Reset     string            `yaml:"reset"`
+					Variables string            `yaml:"variables"`
+					Values    map[string]string `yaml:"values"`
+				} `yaml:"template"`
+				ColorSource struct {
+					Title  string            `yaml:"title"`
+					Update string            `yaml:"update"`
+					Colors string            `yaml:"colors"`
+					Sample string            `yaml:"sample"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"color-source"`
+				Thumbnail struct {
+					Title  string            `yaml:"title"`
+					Update string            `yaml:"update"`
+					Values map[string]string `yaml:"values"`
+				} `yaml:"thumbnail"`
 			} `yaml:"fields"`
+			Pages struct {
+				Delivery string `yaml:"delivery"`
+				Display  string `yaml:"display"`
+				Timeline string `yaml:"timeline"`
+				Filters  string `yaml:"filters"`
+				Template string `yaml:"template"`
+			} `yaml:"pages"`
+			Modals struct {
+				TimelineLimits struct {
+					Title      string `yaml:"title"`
+					MaxMembers struct {
+						Label       string `yaml:"label"`
+						Placeholder string `yaml:"placeholder"`
+					} `yaml:"max-members"`
+					MaxSize struct {
+						Label       string `yaml:"label"`
+						Placeholder string `yaml:"placeholder"`
+					} `yaml:"max-size"`
+				} `yaml:"timeline-limits"`
+				Template struct {
+					Title            string    `yaml:"title"`
+					EmbedTitle       textInput `yaml:"embed-title"`
+					EmbedDescription textInput `yaml:"embed-description"`
+					EmbedColor       textInput `yaml:"embed-color"`
+					EmbedFooter      textInput `yaml:"embed-footer"`
+				} `yaml:"template"`
+				Branding struct {
+					Title        string    `yaml:"title"`
+					Update       string    `yaml:"update"`
+					OngoingColor textInput `yaml:"ongoing-color"`
+					EndedColor   textInput `yaml:"ended-color"`
+					ThumbnailURL textInput `yaml:"thumbnail-url"`
+				} `yaml:"branding"`
+			} `yaml:"modals"`
 			Buttons struct {
 				ToggleEnability map[string]string `yaml:"toggle-enability"`
 				Save            struct {
@@ -69,7 +155,11 @@ type Entry struct {
 					Confirm       string `yaml:"confirm"`
 					Cancel        string `yaml:"cancel"`
 				} `yaml:"delete"`
-				Discard string `yaml:"discard"`
+				Discard        string `yaml:"discard"`
+				TimelineLimits string `yaml:"timeline-limits"`
+				Template       string `yaml:"template"`
+				ResetTemplate  string `yaml:"reset-template"`
+				Branding       string `yaml:"branding"`
 			} `yaml:"buttons"`
 			Validate struct {
 				Success string `yaml:"success"`
@@ -78,41 +168,108 @@ type Entry struct {
 					NoChannelFormat       string `yaml:"no-channel-format"`
 					NoPrivacy             string `yaml:"no-privacy"`
 					NoUsernameFormat      string `yaml:"no-username-format"`
+					InvalidMaxMembers     string `yaml:"invalid-max-members"`
+					InvalidMaxSize        string `yaml:"invalid-max-size"`
+					InvalidTemplate       string `yaml:"invalid-template"`
+					InvalidColor          string `yaml:"invalid-color"`
+					InvalidThumbnailURL   string `yaml:"invalid-thumbnail-url"`
+					NoThumbnailURL        string `yaml:"no-thumbnail-url"`
 				} `yaml:"error"`
 			} `yaml:"validate"`
 			Error struct {
 				NotOwner string `yaml:"not-owner"`
 			} `yaml:"error"`
 		} `yaml:"settings"`
+		Expired  string `yaml:"expired"`
+		Rejected string `yaml:"rejected"`
 	} `yaml:"form"`
 	Command struct {
 		Settings struct {
+			Name        string `yaml:"name"`
 			Description string `yaml:"description"`
 			SubCommands struct {
 				Guild struct {
+					Name        string `yaml:"name"`
 					Description string `yaml:"description"`
 				} `yaml:"guild"`
 				Category struct {
+					Name        string `yaml:"name"`
 					Description string `yaml:"description"`
 					Options     struct {
 						Category struct {
+							Name        string `yaml:"name"`
 							Description string `yaml:"description"`
 						} `yaml:"category"`
 					} `yaml:"options"`
 				} `yaml:"category"`
 				Channel struct {
+					Name        string `yaml:"name"`
 					Description string `yaml:"description"`
 					Options     struct {
 						Channel struct {
+							Name        string `yaml:"name"`
 							Description string `yaml:"description"`
 						} `yaml:"channel"`
 					} `yaml:"options"`
 				} `yaml:"channel"`
+				Preview struct {
+					Name        string `yaml:"name"`
+					Description string `yaml:"description"`
+					Content     string `yaml:"content"`
+					Empty       string `yaml:"empty"`
+					Enabled     string `yaml:"enabled"`
+					Disabled    string `yaml:"disabled"`
+					Scope       struct {
+						Title  string            `yaml:"title"`
+						Values map[string]string `yaml:"values"`
+					} `yaml:"scope"`
+					Error string `yaml:"error"`
+				} `yaml:"preview"`
 			} `yaml:"subcommands"`
-			Response struct {
-				ShowForm string `yaml:"show-form"`
-			} `yaml:"response"`
 		} `yaml:"settings"`
+		Stats struct {
+			Name        string `yaml:"name"`
+			Description string `yaml:"description"`
+			Options     struct {
+				Call struct {
+					Name        string `yaml:"name"`
+					Description string `yaml:"description"`
+				} `yaml:"call"`
+				Member struct {
+					Name        string `yaml:"name"`
+					Description string `yaml:"description"`
+				} `yaml:"member"`
+			} `yaml:"options"`
+			Error struct {
+				CallNotFound   string `yaml:"call-not-found"`
+				MemberNotFound string `yaml:"member-not-found"`
+			} `yaml:"error"`
+		} `yaml:"stats"`
+		History struct {
+			Name        string `yaml:"name"`
+			Description string `yaml:"description"`
+			SubCommands struct {
+				Export struct {
+					Name        string `yaml:"name"`
+					Description string `yaml:"description"`
+					Options     struct {
+						Call struct {
+							Name        string `yaml:"name"`
+							Description string `yaml:"description"`
+						} `yaml:"call"`
+						Format struct {
+							Name        string            `yaml:"name"`
+							Description string            `yaml:"description"`
+							Values      map[string]string `yaml:"values"`
+						} `yaml:"format"`
+					} `yaml:"options"`
+					Content string `yaml:"content"`
+				} `yaml:"export"`
+			} `yaml:"subcommands"`
+			Error struct {
+				CallNotFound string `yaml:"call-not-found"`
+			} `yaml:"error"`
+		} `yaml:"history"`
 	} `yaml:"command"`
 
 	Notification struct {
@@ -122,10 +279,11 @@ type Entry struct {
 			TimeElapsed string `yaml:"time-elapsed"`
 			History     string `yaml:"history"`
 			Timeformat  struct {
-				Days    string `yaml:"days"`
-				Hours   string `yaml:"hours"`
-				Minutes string `yaml:"minutes"`
-				Seconds string `yaml:"seconds"`
+				Days      string `yaml:"days"`
+				Hours     string `yaml:"hours"`
+				Minutes   string `yaml:"minutes"`
+				Seconds   string `yaml:"seconds"`
+				Separator string `yaml:"separator"`
 			}
 		} `yaml:"common"`
 		Ongoing struct {
@@ -136,53 +294,110 @@ type Entry struct {
 			Title       string `yaml:"title"`
 			Description string `yaml:"description"`
 		} `yaml:"ended"`
+		Stage struct {
+			Topic    string `yaml:"topic"`
+			Speakers string `yaml:"speakers"`
+			Audience string `yaml:"audience"`
+		} `yaml:"stage"`
+		Stats struct {
+			Title   string `yaml:"title"`
+			Summary string `yaml:"summary"`
+			// EndedSummary describes the calls in the history, the start is formatted with StartFormat of the time package
+			EndedSummary string `yaml:"ended-summary"`
+			StartFormat  string `yaml:"start-format"`
+			Online       string `yaml:"online"`
+			Streaming    string `yaml:"streaming"`
+			Video        string `yaml:"video"`
+			Speaker      string `yaml:"speaker"`
+		} `yaml:"stats"`
+		Timeline struct {
+			Others string `yaml:"others"`
+			Legend struct {
+				Online    string `yaml:"online"`
+				Mute      string `yaml:"mute"`
+				Deaf      string `yaml:"deaf"`
+				Streaming string `yaml:"streaming"`
+				Video     string `yaml:"video"`
+				Speaker   string `yaml:"speaker"`
+			} `yaml:"legend"`
+		} `yaml:"timeline"`
 	} `yaml:"notification"`
+
+	Error struct {
+		Internal           string `yaml:"internal"`
+		Cooldown           string `yaml:"cooldown"`
+		MissingPermissions string `yaml:"missing-permissions"`
+		GuildOnly          string `yaml:"guild-only"`
+	} `yaml:"error"`
 }
 
+// textInput is the text input of the modals
+type textInput struct {
+	Label       string `yaml:"label"`
+	Placeholder string `yaml:"placeholder"`
+}
+
+// DefaultLocale is used for the keys missing in the other locales
+const DefaultLocale = discord.LocaleEnglishUS
+
 func Init(dir string) {
 	locales = make(map[discord.Locale]Entry)
 
-	files, err := os.ReadDir(dir)
+	files, err := localeFiles(dir)
 	if err != nil {
-		panic(fmt.Errorf("failed to read directory: %w", err))
+		panic(err)
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
+	defaultFile, ok := files[DefaultLocale]
+	if !ok {
+		panic(fmt.Errorf("default locale %s is not found", DefaultLocale))
+	}
+
+	for locale, file := range files {
+		// every locale is loaded over the default one, so that a missing key falls back to it
+		chain := []string{defaultFile}
+		if locale != DefaultLocale {
+			chain = append(chain, file)
 		}
 
-		filename := filepath.Join(dir, file.Name())
-		localeEntry, err := load(filename)
+		localeEntry, err := load(chain...)
 		if err != nil {
 			panic(fmt.Errorf("failed to load locale: %w", err))
 		}
 
-		localeName, _ := strings.CutSuffix(file.Name(), filepath.Ext(file.Name()))
-
-		locales[discord.Locale(localeName)] = localeEntry
+		locales[locale] = localeEntry
 	}
 
-	fallback = locales[discord.Locale("en-US")]
+	fallback = locales[DefaultLocale]
 
 }
 
-func load(file string) (Entry, error) {
+// load decodes the files in order, the later ones override the keys of the former ones
+func load(files ...string) (Entry, error) {
+	var entry Entry
+	for _, file := range files {
+		if err := decode(file, &entry); err != nil {
+			return Entry{}, err
+		}
+	}
+	return entry, nil
+}
+
+func decode(file string, entry *Entry) error {
 	f, err := os.OpenFile(file, os.O_RDONLY, 0)
 
 	if err != nil {
-		return Entry{}, fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 
 	defer f.Close()
 
-	var entry Entry
-	err = yaml.NewDecoder(f).Decode(&entry)
+	err = yaml.NewDecoder(f).Decode(entry)
 	if err != nil {
-		return Entry{}, fmt.Errorf("failed to decode yaml: %w", err)
+		return fmt.Errorf("failed to decode yaml: %w", err)
 	}
 
-	return entry, nil
+	return nil
 
 }
 
@@ -194,13 +409,13 @@ func Get(locale discord.Locale) Entry {
 	return fallback
 }
 
-// Help localization of the bot commands
+// Help localization of the bot commands, the value is given for every loaded locale
 func Localizations(valueFunc func(entry Entry) string) map[discord.Locale]string {
-	locales := make(map[discord.Locale]string)
+	result := make(map[discord.Locale]string, len(locales))
 
-	for locale := range locales {
-		locales[locale] = valueFunc(Get(locale))
+	for locale, entry := range locales {
+		result[locale] = valueFunc(entry)
 	}
 
-	return locales
+	return result
 }
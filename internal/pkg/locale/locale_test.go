@@ -1,6 +1,8 @@
 package locale
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -24,3 +26,47 @@ func TestGetInGoRoutine(t *testing.T) {
 	wg.Wait()
 
 }
+
+func TestFallbackPerKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "en-US.yml", "form:\n  expired: expired\n  settings:\n    validate:\n      success: saved\n")
+	writeFile(t, dir, "ja.yml", "form:\n  settings:\n    validate:\n      success: 保存しました\n")
+
+	Init(dir)
+	defer Init("../../../locales")
+
+	assert.Equal(t, "保存しました", Get("ja").Form.Settings.Validate.Success)
+	assert.Equal(t, "expired", Get("ja").Form.Expired)
+	assert.Equal(t, "saved", Get("fr").Form.Settings.Validate.Success)
+}
+
+func TestCheckLocales(t *testing.T) {
+	problems, err := Check("../../../locales")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestCheckReportsProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "en-US.yml", "form:\n  expired: expired\n  unknown: unknown\nerror:\n  cooldown: wait %[1]d seconds\n")
+	writeFile(t, dir, "ja.yml", "form:\n  extra: extra\nerror:\n  cooldown: お待ちください\n")
+
+	problems, err := Check(dir)
+	assert.NoError(t, err)
+
+	found := make([]string, len(problems))
+	for i, p := range problems {
+		found[i] = p.String()
+	}
+	assert.Contains(t, found, "en-US: form.unknown: extra")
+	assert.Contains(t, found, "en-US: error.internal: missing")
+	assert.Contains(t, found, "ja: form.expired: missing")
+	assert.Contains(t, found, "ja: form.extra: extra")
+	assert.Contains(t, found, `ja: error.cooldown: placeholder (expected "%[1]d", got "")`)
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,427 @@
+package locale
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+var (
+	ErrUnclosedArgument = errors.New("unclosed argument")
+	ErrUnexpectedBrace  = errors.New("unexpected closing brace")
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrMissingOther     = errors.New("plural and select must have the other case")
+)
+
+// Args are the named arguments of a message
+type Args map[string]any
+
+// Message is a compiled message in the subset of the ICU message format:
+//
+//	{name}                                   the argument
+//	{count, plural, =0 {none} one {# item} other {# items}}
+//	{kind, select, stage {Stage} other {Call}}
+//
+// in the cases of plural, # is the number. a quote escapes the braces and #, e.g. '{' and '#',
+// and two quotes are a quote. the arguments not given are left as they are.
+type Message struct {
+	nodes []node
+}
+
+type node interface {
+	format(sb *strings.Builder, l discord.Locale, args Args, number string)
+}
+
+type textNode string
+
+func (n textNode) format(sb *strings.Builder, _ discord.Locale, _ Args, _ string) {
+	sb.WriteString(string(n))
+}
+
+// numberNode is # in the cases of plural
+type numberNode struct{}
+
+func (numberNode) format(sb *strings.Builder, _ discord.Locale, _ Args, number string) {
+	sb.WriteString(number)
+}
+
+type argumentNode struct {
+	name string
+}
+
+func (n argumentNode) format(sb *strings.Builder, _ discord.Locale, args Args, _ string) {
+	value, ok := args[n.name]
+	if !ok {
+		sb.WriteString("{" + n.name + "}")
+		return
+	}
+	sb.WriteString(formatValue(value))
+}
+
+type pluralNode struct {
+	name  string
+	cases map[string][]node
+}
+
+func (n pluralNode) format(sb *strings.Builder, l discord.Locale, args Args, number string) {
+	value, ok := args[n.name]
+	if !ok {
+		sb.WriteString("{" + n.name + "}")
+		return
+	}
+
+	count, _ := strconv.ParseFloat(formatValue(value), 64)
+	nodes, ok := n.cases["="+formatValue(value)]
+	if !ok {
+		nodes, ok = n.cases[PluralCategory(l, count)]
+	}
+	if !ok {
+		nodes = n.cases["other"]
+	}
+	formatNodes(sb, nodes, l, args, formatValue(value))
+}
+
+type selectNode struct {
+	name  string
+	cases map[string][]node
+}
+
+func (n selectNode) format(sb *strings.Builder, l discord.Locale, args Args, number string) {
+	nodes, ok := n.cases[formatValue(args[n.name])]
+	if !ok {
+		nodes = n.cases["other"]
+	}
+	formatNodes(sb, nodes, l, args, number)
+}
+
+func formatNodes(sb *strings.Builder, nodes []node, l discord.Locale, args Args, number string) {
+	for _, n := range nodes {
+		n.format(sb, l, args, number)
+	}
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Compile parses the message.
+func Compile(message string) (*Message, error) {
+	p := &parser{src: []rune(message)}
+	nodes, err := p.parseMessage(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("%w at %d", ErrUnexpectedBrace, p.pos)
+	}
+	return &Message{nodes: nodes}, nil
+}
+
+func (m *Message) Format(l discord.Locale, args Args) string {
+	var sb strings.Builder
+	formatNodes(&sb, m.nodes, l, args, "#")
+	return sb.String()
+}
+
+// Args returns the names of the arguments used in the message, sorted
+func (m *Message) Args() []string {
+	found := make(map[string]bool)
+	collectArgs(m.nodes, found)
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func collectArgs(nodes []node, found map[string]bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case argumentNode:
+			found[n.name] = true
+		case pluralNode:
+			found[n.name] = true
+			for _, c := range n.cases {
+				collectArgs(c, found)
+			}
+		case selectNode:
+			found[n.name] = true
+			for _, c := range n.cases {
+				collectArgs(c, found)
+			}
+		}
+	}
+}
+
+// compiled is the result of compiling the message, cached by the message
+type compiled struct {
+	message *Message
+	err     error
+}
+
+// compiledMessages caches the compiled messages, since the messages are formatted on every notification
+var compiledMessages sync.Map
+
+// Format formats the message with the arguments,
+// the message is returned as it is if it cannot be compiled.
+func Format(l discord.Locale, message string, args Args) string {
+	c, ok := compiledMessages.Load(message)
+	if !ok {
+		m, err := Compile(message)
+		if err != nil {
+			// reported only once, the broken message is cached as well
+			fmt.Fprintln(os.Stderr, "failed to compile message:", err)
+		}
+		c, _ = compiledMessages.LoadOrStore(message, compiled{message: m, err: err})
+	}
+
+	result := c.(compiled)
+	if result.err != nil {
+		return message
+	}
+	return result.message.Format(l, args)
+}
+
+type parser struct {
+	src []rune
+	pos int
+}
+
+// parseMessage parses until the end or the closing brace of the case, which is not consumed
+func (p *parser) parseMessage(inPlural bool) ([]node, error) {
+	nodes := make([]node, 0)
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch {
+		case r == '\'':
+			p.pos++
+			p.parseQuoted(&text)
+		case r == '{':
+			flush()
+			n, err := p.parseArgument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		case r == '}':
+			flush()
+			return nodes, nil
+		case r == '#' && inPlural:
+			flush()
+			nodes = append(nodes, numberNode{})
+			p.pos++
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+
+	flush()
+	return nodes, nil
+}
+
+// parseQuoted handles the quote after it is consumed
+func (p *parser) parseQuoted(text *strings.Builder) {
+	if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+		text.WriteRune('\'')
+		p.pos++
+		return
+	}
+	// a quote not followed by a special character is just a quote, e.g. don't
+	if p.pos >= len(p.src) || !strings.ContainsRune("{}#", p.src[p.pos]) {
+		text.WriteRune('\'')
+		return
+	}
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		p.pos++
+		if r == '\'' {
+			if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+				text.WriteRune('\'')
+				p.pos++
+				continue
+			}
+			return
+		}
+		text.WriteRune(r)
+	}
+}
+
+// parseArgument parses the argument, # in the cases refers the number of the outer plural if inPlural
+func (p *parser) parseArgument(inPlural bool) (node, error) {
+	start := p.pos
+	p.pos++ // {
+
+	name := p.parseIdentifier()
+	if name == "" {
+		return nil, fmt.Errorf("%w at %d", ErrInvalidArgument, start)
+	}
+
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("%w at %d", ErrUnclosedArgument, start)
+	}
+	if p.src[p.pos] == '}' {
+		p.pos++
+		return argumentNode{name: name}, nil
+	}
+	if p.src[p.pos] != ',' {
+		return nil, fmt.Errorf("%w at %d", ErrInvalidArgument, start)
+	}
+	p.pos++
+
+	kind := p.parseIdentifier()
+	if kind != "plural" && kind != "select" {
+		return nil, fmt.Errorf("%w: unknown type %q at %d", ErrInvalidArgument, kind, start)
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != ',' {
+		return nil, fmt.Errorf("%w at %d", ErrInvalidArgument, start)
+	}
+	p.pos++
+
+	cases := make(map[string][]node)
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("%w at %d", ErrUnclosedArgument, start)
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			break
+		}
+
+		key := p.parseKey()
+		if key == "" {
+			return nil, fmt.Errorf("%w: no case at %d", ErrInvalidArgument, p.pos)
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+			return nil, fmt.Errorf("%w: no message of the case %q at %d", ErrInvalidArgument, key, p.pos)
+		}
+		p.pos++
+
+		nodes, err := p.parseMessage(inPlural || kind == "plural")
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("%w at %d", ErrUnclosedArgument, start)
+		}
+		p.pos++ // }
+		cases[key] = nodes
+	}
+
+	if _, ok := cases["other"]; !ok {
+		return nil, fmt.Errorf("%w at %d", ErrMissingOther, start)
+	}
+	if kind == "plural" {
+		return pluralNode{name: name, cases: cases}, nil
+	}
+	return selectNode{name: name, cases: cases}, nil
+}
+
+func (p *parser) parseIdentifier() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if r != '_' && r != '-' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) parseKey() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\n{}", p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\n", p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// PluralCategory returns the plural category of the number in the language of the locale,
+// one of zero, one, two, few, many and other.
+func PluralCategory(l discord.Locale, n float64) string {
+	language, _, _ := strings.Cut(string(l), "-")
+	integer := n == float64(int64(n))
+	i := int64(n)
+
+	switch language {
+	// no plural forms
+	case "ja", "zh", "ko", "th", "vi", "id":
+		return "other"
+	// 0 and 1 are singular
+	case "fr", "pt", "hi":
+		if n >= 0 && n < 2 {
+			return "one"
+		}
+		return "other"
+	// slavic languages
+	case "ru", "uk":
+		if !integer {
+			return "other"
+		}
+		switch {
+		case i%10 == 1 && i%100 != 11:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		if !integer {
+			return "other"
+		}
+		switch {
+		case i == 1:
+			return "one"
+		case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	// english and the most of european languages
+	default:
+		if integer && i == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
@@ -0,0 +1,68 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	hours := "{count, plural, =0 {no hours} one {# hour} other {# hours}}"
+
+	tests := []struct {
+		locale  discord.Locale
+		message string
+		args    Args
+		want    string
+	}{
+		{discord.LocaleEnglishUS, "A call in {channel}", Args{"channel": "general"}, "A call in general"},
+		{discord.LocaleEnglishUS, hours, Args{"count": 0}, "no hours"},
+		{discord.LocaleEnglishUS, hours, Args{"count": 1}, "1 hour"},
+		{discord.LocaleEnglishUS, hours, Args{"count": 2}, "2 hours"},
+		{discord.LocaleJapanese, "{count, plural, one {# 個} other {# 個}}", Args{"count": 1}, "1 個"},
+		{discord.LocaleFrench, "{count, plural, one {# heure} other {# heures}}", Args{"count": 0}, "0 heure"},
+		{discord.LocaleRussian, "{count, plural, one {# час} few {# часа} many {# часов} other {# часа}}", Args{"count": 3}, "3 часа"},
+		{discord.LocaleEnglishUS, "{kind, select, stage {Stage} other {Call}} in {channel}", Args{"kind": "stage", "channel": "x"}, "Stage in x"},
+		{discord.LocaleEnglishUS, "{count, plural, other {{kind, select, a {#a} other {#}}}}", Args{"count": 3, "kind": "a"}, "3a"},
+		{discord.LocaleEnglishUS, "'{quoted}' don't '#'", nil, "{quoted} don't #"},
+		{discord.LocaleEnglishUS, "{missing} is left", nil, "{missing} is left"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Format(tt.locale, tt.message, tt.args), tt.message)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, message := range []string{
+		"{unclosed",
+		"closed}",
+		"{count, plural, one {# item}}",
+		"{count, number}",
+		"{}",
+	} {
+		_, err := Compile(message)
+		assert.Error(t, err, message)
+	}
+}
+
+func TestFormatCachesMessages(t *testing.T) {
+	message := "{count, plural, one {# item}}"
+	// the broken message is left as it is, every time
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, message, Format(discord.LocaleEnglishUS, message, Args{"count": 1}))
+	}
+	_, ok := compiledMessages.Load(message)
+	assert.True(t, ok)
+
+	hours := "{count, plural, one {# hour} other {# hours}}"
+	assert.Equal(t, "1 hour", Format(discord.LocaleEnglishUS, hours, Args{"count": 1}))
+	assert.Equal(t, "2 hours", Format(discord.LocaleEnglishUS, hours, Args{"count": 2}))
+}
+
+func TestArgs(t *testing.T) {
+	m, err := Compile("{b} {a, plural, other {{c}}}")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, m.Args())
+}
@@ -0,0 +1,86 @@
+package rule
+
+// DefaultColor is the color of the embeds unless the rule chooses one
+const DefaultColor = 0x547443
+
+// ColorSource decides where the color of the embeds comes from
+type ColorSource int
+
+const (
+	// ColorSourceFixed uses the colors chosen for the ongoing and ended states
+	ColorSourceFixed ColorSource = iota
+	// ColorSourceChannel derives the colors from the voice channel, so that each channel has its own
+	ColorSourceChannel
+	// ColorSourceStarterRole uses the color of the highest colored role of the member who started the call
+	ColorSourceStarterRole
+)
+
+func (s ColorSource) String() string {
+	switch s {
+	case ColorSourceFixed:
+		return "fixed"
+	case ColorSourceChannel:
+		return "channel"
+	case ColorSourceStarterRole:
+		return "starter_role"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseColorSource treats an empty string as fixed,
+// so that rules saved before the color was introduced keep working.
+func ParseColorSource(s string) ColorSource {
+	switch s {
+	case "", "fixed":
+		return ColorSourceFixed
+	case "channel":
+		return ColorSourceChannel
+	case "starter_role":
+		return ColorSourceStarterRole
+	default:
+		return ColorSource(-1)
+	}
+}
+
+// Thumbnail is the image shown at the corner of the embeds
+type Thumbnail int
+
+const (
+	ThumbnailNone Thumbnail = iota
+	ThumbnailGuildIcon
+	ThumbnailStarterAvatar
+	// ThumbnailCustom uses the url set by the rule
+	ThumbnailCustom
+)
+
+func (t Thumbnail) String() string {
+	switch t {
+	case ThumbnailNone:
+		return "none"
+	case ThumbnailGuildIcon:
+		return "guild_icon"
+	case ThumbnailStarterAvatar:
+		return "starter_avatar"
+	case ThumbnailCustom:
+		return "custom"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseThumbnail treats an empty string as none, same as ParseColorSource.
+func ParseThumbnail(s string) Thumbnail {
+	switch s {
+	case "", "none":
+		return ThumbnailNone
+	case "guild_icon":
+		return ThumbnailGuildIcon
+	case "starter_avatar":
+		return ThumbnailStarterAvatar
+	case "custom":
+		return ThumbnailCustom
+	default:
+		return Thumbnail(-1)
+	}
+}
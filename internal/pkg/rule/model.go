@@ -14,6 +14,22 @@ type RuleModel struct {
 	History             string
 	UserFormat          string
 	ChannelFormat       string
+	TimelineFormat      string
+	TimelineTheme       string
+	TimelineLegend      bool
+	TimelineLabels      bool
+	TimelineMaxMembers  int
+	TimelineMaxSize     int
+	StageLiveOnly       bool
+	TemplateTitle       string
+	TemplateDescription string
+	TemplateColor       string
+	TemplateFooter      string
+	ColorSource         string
+	OngoingColor        *int
+	EndedColor          *int
+	Thumbnail           string
+	ThumbnailURL        string
 }
 
 func (m RuleModel) toRule() (Scope, snowflake.ID, Rule) {
@@ -23,6 +39,24 @@ func (m RuleModel) toRule() (Scope, snowflake.ID, Rule) {
 		History:             ParseHistory(m.History),
 		UserFormat:          ParseUserFormat(m.UserFormat),
 		ChannelFormat:       ParseChannelFormat(m.ChannelFormat),
+		TimelineFormat:      ParseTimelineFormat(m.TimelineFormat),
+		TimelineTheme:       ParseTimelineTheme(m.TimelineTheme),
+		TimelineLegend:      m.TimelineLegend,
+		TimelineLabels:      m.TimelineLabels,
+		TimelineMaxMembers:  m.TimelineMaxMembers,
+		TimelineMaxSize:     m.TimelineMaxSize,
+		StageLiveOnly:       m.StageLiveOnly,
+		Template: Template{
+			Title:       m.TemplateTitle,
+			Description: m.TemplateDescription,
+			Color:       m.TemplateColor,
+			Footer:      m.TemplateFooter,
+		},
+		ColorSource:  ParseColorSource(m.ColorSource),
+		OngoingColor: m.OngoingColor,
+		EndedColor:   m.EndedColor,
+		Thumbnail:    ParseThumbnail(m.Thumbnail),
+		ThumbnailURL: m.ThumbnailURL,
 	}
 }
 
@@ -35,5 +69,21 @@ func newModel(scope Scope, id snowflake.ID, rule Rule) RuleModel {
 		History:             rule.History.String(),
 		UserFormat:          rule.UserFormat.String(),
 		ChannelFormat:       rule.ChannelFormat.String(),
+		TimelineFormat:      rule.TimelineFormat.String(),
+		TimelineTheme:       rule.TimelineTheme.String(),
+		TimelineLegend:      rule.TimelineLegend,
+		TimelineLabels:      rule.TimelineLabels,
+		TimelineMaxMembers:  rule.TimelineMaxMembers,
+		TimelineMaxSize:     rule.TimelineMaxSize,
+		StageLiveOnly:       rule.StageLiveOnly,
+		TemplateTitle:       rule.Template.Title,
+		TemplateDescription: rule.Template.Description,
+		TemplateColor:       rule.Template.Color,
+		TemplateFooter:      rule.Template.Footer,
+		ColorSource:         rule.ColorSource.String(),
+		OngoingColor:        rule.OngoingColor,
+		EndedColor:          rule.EndedColor,
+		Thumbnail:           rule.Thumbnail.String(),
+		ThumbnailURL:        rule.ThumbnailURL,
 	}
 }
@@ -8,4 +8,25 @@ type Rule struct {
 	History             History
 	UserFormat          UserFormat
 	ChannelFormat       ChannelFormat
+	TimelineFormat      TimelineFormat
+	TimelineTheme       TimelineTheme
+	TimelineLegend      bool
+	TimelineLabels      bool
+	// TimelineMaxMembers aggregates the rest of the members into a single entry, zero means no limit
+	TimelineMaxMembers int
+	// TimelineMaxSize limits the longer side of the timeline in pixels, zero means no limit
+	TimelineMaxSize int
+	// StageLiveOnly skips calls on stage channels unless a stage instance is live
+	StageLiveOnly bool
+	// Template overrides the embeds of the notifications
+	Template Template
+	// ColorSource decides the color of the embeds, the colors below are used for ColorSourceFixed
+	ColorSource ColorSource
+	// OngoingColor and EndedColor are the colors of the states, nil means DefaultColor.
+	// they are pointers since black is a valid color.
+	OngoingColor *int
+	EndedColor   *int
+	Thumbnail    Thumbnail
+	// ThumbnailURL is used for ThumbnailCustom
+	ThumbnailURL string
 }
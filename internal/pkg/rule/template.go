@@ -0,0 +1,160 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/makeitchaccha/ringring/internal/pkg/locale"
+)
+
+// Template overrides the embeds of the notifications, the empty fields are not overridden.
+// every field is a message of the locale package, which can use the TemplateVariables,
+// e.g. {starter} started a call in {channel}.
+type Template struct {
+	Title       string
+	Description string
+	// Color is formatted into a hex color like #547443
+	Color  string
+	Footer string
+}
+
+const (
+	TemplateVariableChannel      = "channel"
+	TemplateVariableStarter      = "starter"
+	TemplateVariableParticipants = "participants"
+	TemplateVariableDuration     = "duration"
+	TemplateVariableStart        = "start"
+	// TemplateVariableState is either TemplateStateOngoing or TemplateStateEnded
+	TemplateVariableState = "state"
+)
+
+const (
+	TemplateStateOngoing = "ongoing"
+	TemplateStateEnded   = "ended"
+)
+
+var TemplateVariables = []string{
+	TemplateVariableChannel,
+	TemplateVariableStarter,
+	TemplateVariableParticipants,
+	TemplateVariableDuration,
+	TemplateVariableStart,
+	TemplateVariableState,
+}
+
+var (
+	ErrUnknownVariable = errors.New("unknown variable")
+	ErrInvalidColor    = errors.New("invalid color")
+)
+
+// TemplateField is the field of the template, used to report which one is invalid
+type TemplateField int
+
+const (
+	TemplateFieldTitle TemplateField = iota
+	TemplateFieldDescription
+	TemplateFieldColor
+	TemplateFieldFooter
+)
+
+func (f TemplateField) String() string {
+	switch f {
+	case TemplateFieldTitle:
+		return "title"
+	case TemplateFieldDescription:
+		return "description"
+	case TemplateFieldColor:
+		return "color"
+	case TemplateFieldFooter:
+		return "footer"
+	default:
+		return "unknown"
+	}
+}
+
+type TemplateError struct {
+	Field TemplateField
+	Err   error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+func (t Template) IsEmpty() bool {
+	return t == Template{}
+}
+
+// Get returns the field of the template
+func (t Template) Get(field TemplateField) string {
+	switch field {
+	case TemplateFieldTitle:
+		return t.Title
+	case TemplateFieldDescription:
+		return t.Description
+	case TemplateFieldColor:
+		return t.Color
+	case TemplateFieldFooter:
+		return t.Footer
+	default:
+		return ""
+	}
+}
+
+// Validate reports the first invalid field as TemplateError.
+// the color must be a valid color in both the states.
+func (t Template) Validate() error {
+	for _, field := range []TemplateField{TemplateFieldTitle, TemplateFieldDescription, TemplateFieldColor, TemplateFieldFooter} {
+		value := t.Get(field)
+		if value == "" {
+			continue
+		}
+
+		m, err := locale.Compile(value)
+		if err != nil {
+			return &TemplateError{Field: field, Err: err}
+		}
+		for _, name := range m.Args() {
+			if !slices.Contains(TemplateVariables, name) {
+				return &TemplateError{Field: field, Err: fmt.Errorf("%w: {%s}", ErrUnknownVariable, name)}
+			}
+		}
+
+		if field != TemplateFieldColor {
+			continue
+		}
+		for _, state := range []string{TemplateStateOngoing, TemplateStateEnded} {
+			color := m.Format(discord.LocaleEnglishUS, locale.Args{TemplateVariableState: state})
+			if _, err := ParseColor(color); err != nil {
+				return &TemplateError{Field: field, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// ParseColor parses the hex color like #547443, either the prefix # or 0x is optional
+func ParseColor(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	hex, ok := strings.CutPrefix(strings.ToLower(s), "#")
+	if !ok {
+		hex = strings.TrimPrefix(hex, "0x")
+	}
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+
+	color, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+	return int(color), nil
+}
@@ -0,0 +1,124 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"#547443", 0x547443, true},
+		{"0x547443", 0x547443, true},
+		{"547443", 0x547443, true},
+		{" #ABCDEF ", 0xABCDEF, true},
+		{"0X000000", 0, true},
+		{"#0x547443", 0, false},
+		{"0x#547443", 0, false},
+		{"##547443", 0, false},
+		{"#54744", 0, false},
+		{"#5474433", 0, false},
+		{"#54744g", 0, false},
+		{"+547443", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		color, err := ParseColor(tt.s)
+		if !tt.ok {
+			assert.ErrorIs(t, err, ErrInvalidColor, tt.s)
+			continue
+		}
+		assert.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, color, tt.s)
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template Template
+		ok       bool
+		// field and err are of the error reported, err is nil for the syntax errors
+		field TemplateField
+		err   error
+	}{
+		{
+			name:     "empty",
+			template: Template{},
+			ok:       true,
+		},
+		{
+			name: "valid",
+			template: Template{
+				Title:       "{starter} started a call in {channel}",
+				Description: "{participants, plural, one {# member} other {# members}} for {duration}",
+				Color:       "{state, select, ongoing {#547443} other {0x202020}}",
+				Footer:      "since {start}",
+			},
+			ok: true,
+		},
+		{
+			name:     "unknown variable",
+			template: Template{Description: "{channel} by {owner}"},
+			field:    TemplateFieldDescription,
+			err:      ErrUnknownVariable,
+		},
+		{
+			name:     "unknown variable in the case",
+			template: Template{Footer: "{state, select, ongoing {{owner}} other {}}"},
+			field:    TemplateFieldFooter,
+			err:      ErrUnknownVariable,
+		},
+		{
+			name:     "plural without other",
+			template: Template{Title: "{participants, plural, one {# member}}"},
+			field:    TemplateFieldTitle,
+		},
+		{
+			name:     "unclosed plural",
+			template: Template{Title: "{participants, plural, one {# member} other {# members}"},
+			field:    TemplateFieldTitle,
+		},
+		{
+			name:     "color invalid only while ongoing",
+			template: Template{Color: "{state, select, ongoing {green} other {#547443}}"},
+			field:    TemplateFieldColor,
+			err:      ErrInvalidColor,
+		},
+		{
+			name:     "color invalid only after ended",
+			template: Template{Color: "{state, select, ended {#5474} other {#547443}}"},
+			field:    TemplateFieldColor,
+			err:      ErrInvalidColor,
+		},
+		{
+			name:     "color with both prefixes",
+			template: Template{Color: "#0x547443"},
+			field:    TemplateFieldColor,
+			err:      ErrInvalidColor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+
+			var templateErr *TemplateError
+			if assert.ErrorAs(t, err, &templateErr) {
+				assert.Equal(t, tt.field, templateErr.Field)
+			}
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
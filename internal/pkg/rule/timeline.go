@@ -0,0 +1,95 @@
+package rule
+
+type TimelineFormat int
+
+const (
+	TimelineFormatPNG TimelineFormat = iota
+	TimelineFormatSVG
+	TimelineFormatReplay
+)
+
+func (f TimelineFormat) String() string {
+	switch f {
+	case TimelineFormatPNG:
+		return "png"
+	case TimelineFormatSVG:
+		return "svg"
+	case TimelineFormatReplay:
+		return "replay"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimelineFormat treats an empty string as png,
+// so that rules saved before the format was introduced keep working.
+func ParseTimelineFormat(s string) TimelineFormat {
+	switch s {
+	case "", "png":
+		return TimelineFormatPNG
+	case "svg":
+		return TimelineFormatSVG
+	case "replay":
+		return TimelineFormatReplay
+	default:
+		return TimelineFormat(-1)
+	}
+}
+
+// Filename returns the name of the attachment the timeline is sent as.
+func (f TimelineFormat) Filename() string {
+	switch f {
+	case TimelineFormatSVG:
+		return "timeline.svg"
+	case TimelineFormatReplay:
+		return "timeline.gif"
+	default:
+		return "thumbnail.png"
+	}
+}
+
+// IsEmbeddable reports whether discord can display the timeline inside an embed.
+// svg is sent as a plain attachment instead.
+func (f TimelineFormat) IsEmbeddable() bool {
+	return f != TimelineFormatSVG
+}
+
+type TimelineTheme int
+
+const (
+	TimelineThemeLight TimelineTheme = iota
+	TimelineThemeDark
+	TimelineThemeHighContrast
+	TimelineThemeColorblind
+)
+
+func (t TimelineTheme) String() string {
+	switch t {
+	case TimelineThemeLight:
+		return "light"
+	case TimelineThemeDark:
+		return "dark"
+	case TimelineThemeHighContrast:
+		return "high_contrast"
+	case TimelineThemeColorblind:
+		return "colorblind"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimelineTheme treats an empty string as light, as same as ParseTimelineFormat.
+func ParseTimelineTheme(s string) TimelineTheme {
+	switch s {
+	case "", "light":
+		return TimelineThemeLight
+	case "dark":
+		return TimelineThemeDark
+	case "high_contrast":
+		return TimelineThemeHighContrast
+	case "colorblind":
+		return TimelineThemeColorblind
+	default:
+		return TimelineTheme(-1)
+	}
+}
@@ -1,47 +1,235 @@
 package util
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"image"
 	"image/color"
+	"math"
+	"sort"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
+const (
+	// clusters is the number of clusters used to extract the dominant colors
+	clusters = 5
+	// maxSamples limits the number of pixels clustered, larger images are sampled
+	maxSamples   = 4096
+	maxIteration = 10
+)
+
+// ExtractMainColor returns the most dominant color of the image.
+// white like and transparent pixels are ignored, and black is returned if nothing is left.
 func ExtractMainColor(img image.Image) color.Color {
-	bounds := img.Bounds()
-	colorCount := make(map[color.Color]int)
+	colors := ExtractDominantColors(img)
+	if len(colors) == 0 {
+		return color.Black
+	}
+	return colors[0]
+}
+
+// ExtractDominantColors clusters the pixels with k-means in Lab space,
+// and returns the centers of the clusters from the most dominant one.
+func ExtractDominantColors(img image.Image) []color.Color {
+	samples := samplePixels(img)
+	if len(samples) == 0 {
+		return nil
+	}
 
-	// HACK: Prevent all white or transparent images from being considered
-	colorCount[color.Black] = 1
+	centers := initialCenters(samples, clusters)
+	assignments := make([]int, len(samples))
+	counts := make([]int, len(centers))
 
-	// Iterate over each pixel
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			c := img.At(x, y)
-			r, g, b, a := c.RGBA()
-			// Ignore white like colors
-			if r > 0xBFFF && g > 0xBFFF && b > 0xBFFF {
+	for iteration := 0; iteration < maxIteration; iteration++ {
+		changed := iteration == 0
+		for i, s := range samples {
+			if nearest := nearestCenter(centers, s); nearest != assignments[i] {
+				assignments[i] = nearest
+				changed = true
+			}
+		}
+
+		sums := make([][3]float64, len(centers))
+		for i := range counts {
+			counts[i] = 0
+		}
+		for i, s := range samples {
+			c := assignments[i]
+			sums[c][0] += s[0]
+			sums[c][1] += s[1]
+			sums[c][2] += s[2]
+			counts[c]++
+		}
+		for i := range centers {
+			if counts[i] == 0 {
 				continue
 			}
-			// also ignore transparent pixels
-			if a == 0 {
+			n := float64(counts[i])
+			centers[i] = [3]float64{sums[i][0] / n, sums[i][1] / n, sums[i][2] / n}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	order := make([]int, len(centers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	colors := make([]color.Color, 0, len(centers))
+	for _, i := range order {
+		if counts[i] == 0 {
+			continue
+		}
+		colors = append(colors, colorful.Lab(centers[i][0], centers[i][1], centers[i][2]).Clamped())
+	}
+	return colors
+}
+
+// samplePixels converts the pixels into Lab space, skipping white like and transparent ones.
+func samplePixels(img image.Image) [][3]float64 {
+	bounds := img.Bounds()
+	step := 1
+	if pixels := bounds.Dx() * bounds.Dy(); pixels > maxSamples {
+		step = int(math.Ceil(math.Sqrt(float64(pixels) / maxSamples)))
+	}
+
+	samples := make([][3]float64, 0, maxSamples)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, a := img.At(x, y).RGBA()
+			// ignore transparent pixels
+			if a < 0x8000 {
 				continue
 			}
-			colorCount[c]++
+			// ignore white like colors
+			if r > 0xBFFF && g > 0xBFFF && b > 0xBFFF {
+				continue
+			}
+			c := colorful.Color{R: float64(r) / float64(a), G: float64(g) / float64(a), B: float64(b) / float64(a)}
+			l, la, lb := c.Lab()
+			samples = append(samples, [3]float64{l, la, lb})
 		}
 	}
+	return samples
+}
 
-	// Find the most frequent color
-	var mainColor color.Color
-	maxCount := 0
-	for c, count := range colorCount {
-		if count > maxCount {
-			maxCount = count
-			mainColor = c
+// initialCenters picks the centers deterministically, from the mean and then the farthest samples.
+func initialCenters(samples [][3]float64, k int) [][3]float64 {
+	var mean [3]float64
+	for _, s := range samples {
+		mean[0] += s[0]
+		mean[1] += s[1]
+		mean[2] += s[2]
+	}
+	n := float64(len(samples))
+	centers := [][3]float64{{mean[0] / n, mean[1] / n, mean[2] / n}}
+
+	for len(centers) < k {
+		farthest, distance := -1, 0.0
+		for i, s := range samples {
+			d := distanceSq(s, centers[nearestCenter(centers, s)])
+			if d > distance {
+				farthest, distance = i, d
+			}
+		}
+		// no more distinct samples
+		if farthest < 0 {
+			break
+		}
+		centers = append(centers, samples[farthest])
+	}
+	return centers
+}
+
+func nearestCenter(centers [][3]float64, s [3]float64) int {
+	nearest, distance := 0, math.Inf(1)
+	for i, c := range centers {
+		if d := distanceSq(c, s); d < distance {
+			nearest, distance = i, d
+		}
+	}
+	return nearest
+}
+
+func distanceSq(a, b [3]float64) float64 {
+	d0, d1, d2 := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return d0*d0 + d1*d1 + d2*d2
+}
+
+// hueStep is the hue rotated to find a distinct color, in degrees
+const hueStep = 30.0
+
+// AssignDistinctColors picks a color for each of the candidates, in order.
+// candidates are the colors preferred for the entry, from the most preferred one.
+// a candidate closer than minDistance (in Lab space) to any color already picked is skipped,
+// and if no candidate is distinct enough, the hue of the first candidate is rotated until it is.
+func AssignDistinctColors(candidates [][]color.Color, minDistance float64) []color.Color {
+	assigned := make([]colorful.Color, 0, len(candidates))
+	result := make([]color.Color, 0, len(candidates))
+
+	isDistinct := func(c colorful.Color) bool {
+		for _, a := range assigned {
+			if c.DistanceLab(a) < minDistance {
+				return false
+			}
 		}
+		return true
 	}
 
-	return mainColor
+	for _, preferred := range candidates {
+		picked, found := colorful.Color{}, false
+		for _, candidate := range preferred {
+			c, ok := colorful.MakeColor(candidate)
+			if ok && isDistinct(c) {
+				picked, found = c, true
+				break
+			}
+		}
+
+		if !found && len(preferred) > 0 {
+			base, ok := colorful.MakeColor(preferred[0])
+			if !ok {
+				base = colorful.Color{}
+			}
+			h, c, l := base.Hcl()
+			// grayish colors cannot be distinguished by the hue
+			c = math.Max(c, 0.3)
+			picked = colorful.Hcl(h, c, l).Clamped()
+			for step := 1.0; step*hueStep < 360; step++ {
+				candidate := colorful.Hcl(math.Mod(h+step*hueStep, 360), c, l).Clamped()
+				if isDistinct(candidate) {
+					picked = candidate
+					break
+				}
+			}
+		}
+
+		assigned = append(assigned, picked)
+		result = append(result, picked)
+	}
+
+	return result
+}
+
+// HashColor derives a stable color from the seed, e.g. an id, with the chroma and luminance of Hcl space.
+func HashColor(seed uint64, chroma, luminance float64) color.Color {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	hue := float64(h.Sum64() % 360)
+	return colorful.Hcl(hue, chroma, luminance).Clamped()
+}
+
+// ColorToInt converts the color into 0xRRGGBB, used by the embeds
+func ColorToInt(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	return int(r>>8)<<16 | int(g>>8)<<8 | int(b>>8)
 }
 
 func TransformColorWithSpecificLuminance(c color.Color, targetLuminance float64) color.Color {
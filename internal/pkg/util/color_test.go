@@ -0,0 +1,183 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func solid(c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func distance(a, b color.Color) float64 {
+	ca, _ := colorful.MakeColor(a)
+	cb, _ := colorful.MakeColor(b)
+	return ca.DistanceLab(cb)
+}
+
+var (
+	red  = color.RGBA{R: 0xE0, G: 0x20, B: 0x20, A: 0xFF}
+	blue = color.RGBA{R: 0x20, G: 0x40, B: 0xE0, A: 0xFF}
+)
+
+func TestExtractMainColorSolid(t *testing.T) {
+	assert.Less(t, distance(ExtractMainColor(solid(red)), red), 0.01)
+}
+
+func TestExtractMainColorIgnoresWhiteAndTransparent(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			switch {
+			case x < 16:
+				img.Set(x, y, blue)
+			case x < 40:
+				img.Set(x, y, color.White)
+			}
+			// the rest is left transparent
+		}
+	}
+	assert.Less(t, distance(ExtractMainColor(img), blue), 0.01)
+
+	// nothing is left
+	assert.Equal(t, color.Black, ExtractMainColor(solid(color.White)))
+}
+
+func TestExtractMainColorNoisy(t *testing.T) {
+	// 3/4 noisy red and 1/4 noisy blue, like a photo
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	jitter := func(v uint8) uint8 {
+		return uint8(max(0, min(255, int(v)+r.Intn(41)-20)))
+	}
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			c := red
+			if y >= 96 {
+				c = blue
+			}
+			img.Set(x, y, color.RGBA{R: jitter(c.R), G: jitter(c.G), B: jitter(c.B), A: 0xFF})
+		}
+	}
+
+	colors := ExtractDominantColors(img)
+	assert.NotEmpty(t, colors)
+	assert.Less(t, distance(colors[0], red), distance(colors[0], blue))
+
+	// the blue one must be found as one of the clusters
+	found := false
+	for _, c := range colors {
+		if distance(c, blue) < 0.1 {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestAssignDistinctColors(t *testing.T) {
+	similar := color.RGBA{R: 0xE0, G: 0x28, B: 0x20, A: 0xFF}
+	colors := AssignDistinctColors([][]color.Color{
+		{red},
+		// falls back to the second candidate
+		{similar, blue},
+		// no distinct candidate, the hue is rotated
+		{red},
+	}, 0.15)
+
+	assert.Len(t, colors, 3)
+	assert.Less(t, distance(colors[0], red), 0.01)
+	assert.Less(t, distance(colors[1], blue), 0.01)
+	for i := range colors {
+		for j := i + 1; j < len(colors); j++ {
+			assert.GreaterOrEqual(t, distance(colors[i], colors[j]), 0.15)
+		}
+	}
+}
+
+func loadPNG(t *testing.T, name string) image.Image {
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+var (
+	scarlet = color.RGBA{R: 0xE0, G: 0x30, B: 0x20, A: 0xFF}
+	green   = color.RGBA{R: 0x20, G: 0xA0, B: 0x40, A: 0xFF}
+	yellow  = color.RGBA{R: 0xF0, G: 0xC0, B: 0x20, A: 0xFF}
+)
+
+func TestExtractDominantColorsSamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		dominant []color.Color
+	}{
+		// the white face is ignored
+		{"red.png", []color.Color{red}},
+		{"scarlet.png", []color.Color{scarlet, yellow}},
+		{"green.png", []color.Color{green, yellow}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			colors := ExtractDominantColors(loadPNG(t, tt.name))
+			assert.GreaterOrEqual(t, len(colors), len(tt.dominant))
+			for i, want := range tt.dominant {
+				assert.Less(t, distance(colors[i], want), 0.01)
+			}
+		})
+	}
+}
+
+func TestAssignDistinctColorsSamples(t *testing.T) {
+	const minDistance = 0.15
+	candidates := [][]color.Color{
+		ExtractDominantColors(loadPNG(t, "red.png")),
+		// too close to red, so the face is picked
+		ExtractDominantColors(loadPNG(t, "scarlet.png")),
+		// yellow is taken, but green is the first one anyway
+		ExtractDominantColors(loadPNG(t, "green.png")),
+		// the same avatar again, the hue is rotated
+		ExtractDominantColors(loadPNG(t, "red.png")),
+	}
+
+	colors := AssignDistinctColors(candidates, minDistance)
+	assert.Len(t, colors, len(candidates))
+	assert.Less(t, distance(colors[0], red), 0.01)
+	assert.Less(t, distance(colors[1], yellow), 0.01)
+	assert.Less(t, distance(colors[2], green), 0.01)
+	for i := range colors {
+		for j := i + 1; j < len(colors); j++ {
+			assert.GreaterOrEqual(t, distance(colors[i], colors[j]), minDistance)
+		}
+	}
+}
+
+func TestHashColorIsStable(t *testing.T) {
+	assert.Equal(t, ColorToInt(HashColor(42, 0.45, 0.6)), ColorToInt(HashColor(42, 0.45, 0.6)))
+	assert.NotEqual(t, ColorToInt(HashColor(42, 0.45, 0.6)), ColorToInt(HashColor(43, 0.45, 0.6)))
+}
+
+func TestColorToInt(t *testing.T) {
+	assert.Equal(t, 0x547443, ColorToInt(color.RGBA{R: 0x54, G: 0x74, B: 0x43, A: 0xFF}))
+}
@@ -10,3 +10,10 @@ type Command interface {
 	Create() discord.ApplicationCommandCreate
 	Execute(event *events.ApplicationCommandInteractionCreate) error
 }
+
+// Autocompleter is implemented by the commands which have options with autocomplete enabled.
+type Autocompleter interface {
+	Command
+	// Autocomplete should respond with the choices for the focused option
+	Autocomplete(event *events.AutocompleteInteractionCreate) error
+}
@@ -0,0 +1,233 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+type ChangeKind int
+
+const (
+	ChangeCreate ChangeKind = iota
+	ChangeUpdate
+	ChangeDelete
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeCreate:
+		return "create"
+	case ChangeUpdate:
+		return "update"
+	case ChangeDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a difference between the registered and the deployed commands.
+type Change struct {
+	Kind ChangeKind
+	Type discord.ApplicationCommandType
+	Name string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Name)
+}
+
+type deployOptions struct {
+	guildID snowflake.ID
+	dryRun  bool
+}
+
+type DeployOpt func(*deployOptions)
+
+// WithGuild deploys the commands to the guild instead of globally.
+// guild commands are available immediately, which is useful for testing.
+func WithGuild(guildID snowflake.ID) DeployOpt {
+	return func(o *deployOptions) {
+		o.guildID = guildID
+	}
+}
+
+// WithDryRun only computes the changes without deploying.
+func WithDryRun() DeployOpt {
+	return func(o *deployOptions) {
+		o.dryRun = true
+	}
+}
+
+// commandKey identifies the command, the names are unique per type
+type commandKey struct {
+	commandType discord.ApplicationCommandType
+	name        string
+}
+
+// diff compares the commands to create with the deployed commands
+func diff(creates []discord.ApplicationCommandCreate, deployed []discord.ApplicationCommand) ([]Change, error) {
+	existing := make(map[commandKey]discord.ApplicationCommand, len(deployed))
+	for _, c := range deployed {
+		existing[commandKey{c.Type(), c.Name()}] = c
+	}
+
+	changes := make([]Change, 0)
+	for _, create := range creates {
+		key := commandKey{create.Type(), create.CommandName()}
+		c, ok := existing[key]
+		delete(existing, key)
+
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeCreate, Type: key.commandType, Name: key.name})
+			continue
+		}
+
+		equal, err := equalCommand(create, c)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			changes = append(changes, Change{Kind: ChangeUpdate, Type: key.commandType, Name: key.name})
+		}
+	}
+
+	// the rest are not registered anymore
+	stale := make([]Change, 0, len(existing))
+	for key := range existing {
+		stale = append(stale, Change{Kind: ChangeDelete, Type: key.commandType, Name: key.name})
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Name < stale[j].Name
+	})
+
+	return append(changes, stale...), nil
+}
+
+// schemaKeys are compared even if the create omits them, as the deployed one should be empty then
+var schemaKeys = []string{
+	"description",
+	"options",
+	"name_localizations",
+	"description_localizations",
+	"default_member_permissions",
+	"nsfw",
+}
+
+// equalCommand compares the schema of the commands by their json representation,
+// ignoring the fields only the deployed command has, such as the id and the version.
+func equalCommand(create discord.ApplicationCommandCreate, deployed discord.ApplicationCommand) (bool, error) {
+	want, err := toMap(create)
+	if err != nil {
+		return false, err
+	}
+	got, err := toMap(deployed)
+	if err != nil {
+		return false, err
+	}
+
+	keys := make(map[string]struct{}, len(want)+len(schemaKeys))
+	for k := range want {
+		keys[k] = struct{}{}
+	}
+	for _, k := range schemaKeys {
+		keys[k] = struct{}{}
+	}
+
+	for k := range keys {
+		w, g := normalize(k, want[k]), normalize(k, got[k])
+		if isEmpty(w) && isEmpty(g) {
+			continue
+		}
+		if !reflect.DeepEqual(w, g) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal command: %w", err)
+	}
+	return m, nil
+}
+
+// normalize converts the value of the key which disgo cannot distinguish from empty
+func normalize(key string, v any) any {
+	// null permissions of the deployed command are read as "0"
+	if key == "default_member_permissions" && v == "0" {
+		return nil
+	}
+	return v
+}
+
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// deploy overwrites the deployed commands in bulk, only if anything is changed
+func deploy(client bot.Client, creates []discord.ApplicationCommandCreate, opts ...DeployOpt) ([]Change, error) {
+	o := &deployOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rest := client.Rest()
+	appID := client.ApplicationID()
+
+	var (
+		deployed []discord.ApplicationCommand
+		err      error
+	)
+	if o.guildID != 0 {
+		deployed, err = rest.GetGuildCommands(appID, o.guildID, true)
+	} else {
+		deployed, err = rest.GetGlobalCommands(appID, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployed commands: %w", err)
+	}
+
+	changes, err := diff(creates, deployed)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	if o.guildID != 0 {
+		_, err = rest.SetGuildCommands(appID, o.guildID, creates)
+	} else {
+		_, err = rest.SetGlobalCommands(appID, creates)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to overwrite commands: %w", err)
+	}
+	return changes, nil
+}
@@ -2,10 +2,14 @@ package command
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"sort"
 
 	"github.com/disgoorg/disgo/bot"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
 )
 
 var (
@@ -14,15 +18,25 @@ var (
 
 type Manager interface {
 	Register(command Command) error
-	Deploy(client bot.Client) error
+	// Use adds the middlewares wrapping every command, the first one is the outermost
+	Use(middlewares ...middleware.Middleware)
+	// Deploy overwrites the deployed commands with the registered ones, removing the stale ones.
+	// it returns the changes, nothing is requested if nothing is changed.
+	Deploy(client bot.Client, opts ...DeployOpt) ([]Change, error)
+	// Remove removes all the deployed commands of the application.
+	Remove(client bot.Client, opts ...DeployOpt) ([]Change, error)
 
 	// OnCommandInteractionCreate should be called when ApplicationCommandInteractionCreate event is received
 	// This is used to handle the command interaction
 	OnCommandInteractionCreate(event *events.ApplicationCommandInteractionCreate)
+	// OnAutocompleteInteractionCreate should be called when AutocompleteInteractionCreate event is received
+	// This is dispatched to the commands implementing Autocompleter
+	OnAutocompleteInteractionCreate(event *events.AutocompleteInteractionCreate)
 }
 
 type managerImpl struct {
-	commands map[string]Command
+	commands    map[string]Command
+	middlewares []middleware.Middleware
 }
 
 func NewManager() Manager {
@@ -37,13 +51,27 @@ func (m *managerImpl) Register(command Command) error {
 	return nil
 }
 
-func (m *managerImpl) Deploy(client bot.Client) error {
-	for _, command := range m.commands {
-		if _, err := client.Rest().CreateGlobalCommand(client.ApplicationID(), command.Create()); err != nil {
-			return err
-		}
+func (m *managerImpl) Use(middlewares ...middleware.Middleware) {
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+func (m *managerImpl) Deploy(client bot.Client, opts ...DeployOpt) ([]Change, error) {
+	names := make([]string, 0, len(m.commands))
+	for name := range m.commands {
+		names = append(names, name)
 	}
-	return nil
+	sort.Strings(names)
+
+	creates := make([]discord.ApplicationCommandCreate, len(names))
+	for i, name := range names {
+		creates[i] = m.commands[name].Create()
+	}
+
+	return deploy(client, creates, opts...)
+}
+
+func (m *managerImpl) Remove(client bot.Client, opts ...DeployOpt) ([]Change, error) {
+	return deploy(client, []discord.ApplicationCommandCreate{}, opts...)
 }
 
 func (m *managerImpl) OnCommandInteractionCreate(event *events.ApplicationCommandInteractionCreate) {
@@ -54,14 +82,47 @@ func (m *managerImpl) OnCommandInteractionCreate(event *events.ApplicationComman
 		return
 	}
 
-	if err := command.Execute(event); err != nil {
+	handler := middleware.Chain(func(middleware.Event) error {
+		return command.Execute(event)
+	}, m.middlewares...)
+
+	if err := handler(event); err != nil {
 		m.handleCommandError(event, err)
 	}
 }
 
+func (m *managerImpl) OnAutocompleteInteractionCreate(event *events.AutocompleteInteractionCreate) {
+	command, ok := m.commands[event.Data.CommandName]
+	if !ok {
+		return
+	}
+
+	autocompleter, ok := command.(Autocompleter)
+	if !ok {
+		// the command is not supposed to have autocomplete options
+		fmt.Fprintln(os.Stderr, "command does not support autocomplete:", event.Data.CommandName)
+		return
+	}
+
+	// the middlewares are not applied, since autocomplete cannot respond with a message
+	if err := autocompleter.Autocomplete(event); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to autocomplete command:", err)
+	}
+}
+
+// handleCommandError responds the error not handled by the middlewares,
+// without the details unless it is for the user.
 func (m *managerImpl) handleCommandError(event *events.ApplicationCommandInteractionCreate, err error) {
+	message := "Failed to execute command"
+	var userErr *middleware.UserError
+	if errors.As(err, &userErr) {
+		message = userErr.Message
+	} else {
+		fmt.Fprintln(os.Stderr, "failed to execute command:", err)
+	}
+
 	event.CreateMessage(discord.NewMessageCreateBuilder().
-		SetContent("Failed to execute command: " + err.Error()).
+		SetContent(message).
 		SetEphemeral(true).
 		Build())
 }
@@ -0,0 +1,140 @@
+package command
+
+import (
+	"errors"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+var (
+	ErrSubCommandNotFound = errors.New("subcommand not found")
+)
+
+// SubCommand is a subcommand of the command built with Router.
+type SubCommand interface {
+	// Create returns the schema of the subcommand, its name is used to route the interactions
+	Create() discord.ApplicationCommandOptionSubCommand
+	Execute(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error
+}
+
+// SubCommandAutocompleter is implemented by the subcommands which have options with autocomplete enabled.
+type SubCommandAutocompleter interface {
+	SubCommand
+	Autocomplete(event *events.AutocompleteInteractionCreate, data discord.AutocompleteInteractionData) error
+}
+
+// Group is a group of the subcommands.
+type Group struct {
+	Name                     string
+	NameLocalizations        map[discord.Locale]string
+	Description              string
+	DescriptionLocalizations map[discord.Locale]string
+	SubCommands              []SubCommand
+}
+
+var _ Autocompleter = (*Router)(nil)
+
+// Router is a slash command which dispatches the interactions to its subcommands.
+// the options of the command are built from the subcommands and the groups in the order they are added.
+type Router struct {
+	base        discord.SlashCommandCreate
+	options     []discord.ApplicationCommandOption
+	subCommands map[string]SubCommand
+	groups      map[string]map[string]SubCommand
+}
+
+// NewRouter creates the router, the options of base are replaced by the subcommands.
+func NewRouter(base discord.SlashCommandCreate) *Router {
+	return &Router{
+		base:        base,
+		subCommands: make(map[string]SubCommand),
+		groups:      make(map[string]map[string]SubCommand),
+	}
+}
+
+func (r *Router) SubCommand(subCommands ...SubCommand) *Router {
+	for _, sub := range subCommands {
+		option := sub.Create()
+		r.subCommands[option.Name] = sub
+		r.options = append(r.options, option)
+	}
+	return r
+}
+
+func (r *Router) Group(group Group) *Router {
+	subCommands := make(map[string]SubCommand, len(group.SubCommands))
+	options := make([]discord.ApplicationCommandOptionSubCommand, len(group.SubCommands))
+	for i, sub := range group.SubCommands {
+		options[i] = sub.Create()
+		subCommands[options[i].Name] = sub
+	}
+
+	r.groups[group.Name] = subCommands
+	r.options = append(r.options, discord.ApplicationCommandOptionSubCommandGroup{
+		Name:                     group.Name,
+		NameLocalizations:        group.NameLocalizations,
+		Description:              group.Description,
+		DescriptionLocalizations: group.DescriptionLocalizations,
+		Options:                  options,
+	})
+	return r
+}
+
+func (r *Router) Name() string {
+	return r.base.Name
+}
+
+func (r *Router) Create() discord.ApplicationCommandCreate {
+	create := r.base
+	create.Options = r.options
+	return create
+}
+
+func (r *Router) Execute(event *events.ApplicationCommandInteractionCreate) error {
+	data := event.SlashCommandInteractionData()
+
+	sub, err := r.find(data.SubCommandGroupName, data.SubCommandName)
+	if err != nil {
+		return err
+	}
+	return sub.Execute(event, data)
+}
+
+// Autocomplete dispatches the interaction to the subcommand,
+// the subcommands not implementing SubCommandAutocompleter get no choices.
+func (r *Router) Autocomplete(event *events.AutocompleteInteractionCreate) error {
+	data := event.Data
+
+	sub, err := r.find(data.SubCommandGroupName, data.SubCommandName)
+	if err != nil {
+		return err
+	}
+
+	autocompleter, ok := sub.(SubCommandAutocompleter)
+	if !ok {
+		return event.AutocompleteResult(nil)
+	}
+	return autocompleter.Autocomplete(event, data)
+}
+
+func (r *Router) find(groupName, name *string) (SubCommand, error) {
+	if name == nil {
+		return nil, ErrSubCommandNotFound
+	}
+
+	subCommands := r.subCommands
+	if groupName != nil {
+		group, ok := r.groups[*groupName]
+		if !ok {
+			return nil, ErrSubCommandNotFound
+		}
+		subCommands = group
+	}
+
+	sub, ok := subCommands[*name]
+	if !ok {
+		return nil, ErrSubCommandNotFound
+	}
+	return sub, nil
+}
@@ -0,0 +1,116 @@
+package extstd
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size bounded cache which evicts the least recently used entry first.
+// entries also expire after the ttl. it is safe for concurrent use.
+type LRU[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Add(key K, value V)
+	Remove(key K)
+	Len() int
+}
+
+var _ LRU[string, any] = (*lruImpl[string, any])(nil)
+
+type lruImpl[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is the most recently used
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiredAt time.Time
+}
+
+// NewLRU creates a new LRU holding at most size entries.
+// ttl of zero or less means the entries never expire.
+func NewLRU[K comparable, V any](size int, ttl time.Duration) LRU[K, V] {
+	if size <= 0 {
+		panic("size must be positive")
+	}
+	return &lruImpl[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+func (c *lruImpl[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[K, V])
+	if c.expired(entry, time.Now()) {
+		c.remove(elem)
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruImpl[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry := &lruEntry[K, V]{key: key, value: value, expiredAt: now.Add(c.ttl)}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(entry)
+	}
+
+	// expired entries are likely to be the least recently used,
+	// so they are removed from the back as well as the overflowed ones
+	for back := c.order.Back(); back != nil; back = c.order.Back() {
+		if c.order.Len() <= c.size && !c.expired(back.Value.(*lruEntry[K, V]), now) {
+			break
+		}
+		c.remove(back)
+	}
+}
+
+func (c *lruImpl[K, V]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *lruImpl[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *lruImpl[K, V]) expired(entry *lruEntry[K, V], now time.Time) bool {
+	return c.ttl > 0 && !entry.expiredAt.After(now)
+}
+
+func (c *lruImpl[K, V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[K, V]).key)
+}
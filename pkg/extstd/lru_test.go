@@ -0,0 +1,53 @@
+package extstd
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2, 0)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// a is now more recently used than b
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	c.Add("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUExpires(t *testing.T) {
+	c := NewLRU[string, int](2, 10*time.Millisecond)
+	c.Add("a", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRUInGoRoutine(t *testing.T) {
+	c := NewLRU[int, int](8, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Add(i, i)
+			c.Get(i)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8, c.Len())
+}
@@ -0,0 +1,225 @@
+package form
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/pkg/extstd"
+)
+
+var (
+	_ Field = (*Toggle)(nil)
+	_ Field = (*Button)(nil)
+	_ Field = (*ChannelSelect)(nil)
+	_ Field = (*EnumSelect[Bool])(nil)
+)
+
+var (
+	// ErrFieldDisabled is returned when the interaction is on the disabled field, e.g. by a stale client
+	ErrFieldDisabled = errors.New("field is disabled")
+	// ErrInvalidOption is returned when the selected value is not one of the options
+	ErrInvalidOption = errors.New("value is not one of the options")
+)
+
+// Toggle is a button which flips the value.
+type Toggle struct {
+	ID    string
+	Value *Bool
+	// Label returns the label of the button for the current value
+	Label func(value Bool) string
+	// OnChange returns the status shown after the value is flipped
+	OnChange func(value Bool) string
+	Style    discord.ButtonStyle
+	Disabled bool
+}
+
+func (t *Toggle) CustomID() string {
+	return t.ID
+}
+
+func (t *Toggle) inline() bool {
+	return true
+}
+
+func (t *Toggle) disabled() bool {
+	return t.Disabled
+}
+
+func (t *Toggle) component() discord.InteractiveComponent {
+	style := t.Style
+	if style == 0 {
+		style = discord.ButtonStylePrimary
+	}
+	return discord.NewButton(style, t.Label(*t.Value), t.ID, "", 0).WithDisabled(t.Disabled)
+}
+
+func (t *Toggle) handle(r *Router, event *events.ComponentInteractionCreate) error {
+	*t.Value = !*t.Value
+	return event.UpdateMessage(r.render(t.OnChange(*t.Value)))
+}
+
+// Confirm asks the confirmation before the handler of the button runs.
+type Confirm struct {
+	// Status is shown while waiting for the confirmation
+	Status string
+	Label  string
+	Cancel string
+	Style  discord.ButtonStyle
+}
+
+// Button runs the handler when clicked.
+type Button struct {
+	ID       string
+	Label    string
+	Style    discord.ButtonStyle
+	Disabled bool
+	Hidden   bool
+	// Guard is checked before asking the confirmation and before the handler,
+	// the error is shown as the status.
+	Guard   func() error
+	Confirm *Confirm
+	// Handler is responsible to respond the interaction
+	Handler func(event *events.ComponentInteractionCreate) error
+}
+
+func (b *Button) CustomID() string {
+	return b.ID
+}
+
+func (b *Button) inline() bool {
+	return true
+}
+
+func (b *Button) disabled() bool {
+	return b.Disabled
+}
+
+func (b *Button) component() discord.InteractiveComponent {
+	if b.Hidden {
+		return nil
+	}
+	return discord.NewButton(b.Style, b.Label, b.ID, "", 0).WithDisabled(b.Disabled)
+}
+
+func (b *Button) handle(r *Router, event *events.ComponentInteractionCreate) error {
+	if b.Guard != nil {
+		if err := b.Guard(); err != nil {
+			return event.UpdateMessage(r.render(err.Error()))
+		}
+	}
+
+	if b.Confirm != nil && r.pending != nil {
+		*r.pending = b.ID
+		return event.UpdateMessage(r.render(b.Confirm.Status))
+	}
+
+	return b.Handler(event)
+}
+
+// ChannelSelect selects a channel of the types.
+type ChannelSelect struct {
+	ID           string
+	Placeholder  string
+	Value        *extstd.Option[snowflake.ID]
+	ChannelTypes []discord.ChannelType
+	// OnChange returns the status shown after the channel is selected
+	OnChange func(event *events.ComponentInteractionCreate, channelID snowflake.ID) (string, error)
+	Disabled bool
+}
+
+func (c *ChannelSelect) CustomID() string {
+	return c.ID
+}
+
+func (c *ChannelSelect) inline() bool {
+	return false
+}
+
+func (c *ChannelSelect) disabled() bool {
+	return c.Disabled
+}
+
+func (c *ChannelSelect) component() discord.InteractiveComponent {
+	menu := discord.NewChannelSelectMenu(c.ID, c.Placeholder).
+		WithChannelTypes(c.ChannelTypes...).
+		WithMinValues(1).
+		WithMaxValues(1)
+	if (*c.Value).IsSome() {
+		menu = menu.AddDefaultValue((*c.Value).Unwrap())
+	}
+	if c.Disabled {
+		menu = menu.AsDisabled()
+	}
+	return menu
+}
+
+func (c *ChannelSelect) handle(r *Router, event *events.ComponentInteractionCreate) error {
+	value := event.ChannelSelectMenuInteractionData().Values[0]
+	*c.Value = extstd.Some(value)
+
+	status, err := c.OnChange(event, value)
+	if err != nil {
+		return err
+	}
+	return event.UpdateMessage(r.render(status))
+}
+
+// Enum is the value which is encoded by String and decoded by the Parse function of the type.
+type Enum interface {
+	comparable
+	String() string
+}
+
+// EnumSelect selects one of the options.
+type EnumSelect[T Enum] struct {
+	ID          string
+	Placeholder string
+	Value       *extstd.Option[T]
+	Options     []T
+	Parse       func(s string) T
+	// Label returns the label of the option
+	Label func(value T) string
+	// OnChange returns the status shown after the option is selected
+	OnChange func(value T) string
+	Disabled bool
+}
+
+func (e *EnumSelect[T]) CustomID() string {
+	return e.ID
+}
+
+func (e *EnumSelect[T]) inline() bool {
+	return false
+}
+
+func (e *EnumSelect[T]) disabled() bool {
+	return e.Disabled
+}
+
+func (e *EnumSelect[T]) component() discord.InteractiveComponent {
+	options := make([]discord.StringSelectMenuOption, len(e.Options))
+	for i, o := range e.Options {
+		options[i] = discord.NewStringSelectMenuOption(e.Label(o), o.String()).
+			WithDefault((*e.Value).IsSome() && (*e.Value).Unwrap() == o)
+	}
+
+	menu := discord.NewStringSelectMenu(e.ID, e.Placeholder, options...).
+		WithMinValues(1).
+		WithMaxValues(1)
+	if e.Disabled {
+		menu = menu.AsDisabled()
+	}
+	return menu
+}
+
+func (e *EnumSelect[T]) handle(r *Router, event *events.ComponentInteractionCreate) error {
+	value := e.Parse(event.StringSelectMenuInteractionData().Values[0])
+	if !slices.Contains(e.Options, value) {
+		return ErrInvalidOption
+	}
+	*e.Value = extstd.Some(value)
+	return event.UpdateMessage(r.render(e.OnChange(value)))
+}
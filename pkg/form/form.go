@@ -1,6 +1,8 @@
 package form
 
 import (
+	"time"
+
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 )
@@ -10,6 +12,47 @@ type Form interface {
 	Handle(event *events.ComponentInteractionCreate) error
 }
 
+// Persistent is a form which can be restored after the bot restarts.
+type Persistent interface {
+	Form
+	// Kind identifies the factory which restores the form
+	Kind() string
+	// Marshal serializes the current state of the form
+	Marshal() ([]byte, error)
+}
+
+// Guarded is a form which restricts who can interact with it.
+type Guarded interface {
+	Form
+	// Authorize is checked before Handle and HandleModal,
+	// the error is responded to the user if the user is not allowed to interact with the form
+	Authorize(user discord.User) error
+}
+
+// ModalForm is a form which collects free text with modals.
+// the modals must be opened from the components of the form,
+// so that the submissions are routed back to the form.
+type ModalForm interface {
+	Form
+	HandleModal(event *events.ModalSubmitInteractionCreate) error
+}
+
+// Finishable is a form which ends, e.g. when it is saved or discarded.
+// finished forms are forgotten by the manager.
+type Finishable interface {
+	Form
+	IsFinished() bool
+}
+
+// Expirable is a form which overrides the TTL of the manager.
+type Expirable interface {
+	Form
+	TTL() time.Duration
+}
+
+// Factory restores the form from the state serialized by Persistent.Marshal.
+type Factory func(data []byte) (Form, error)
+
 type Bool bool
 
 const (
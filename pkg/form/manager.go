@@ -1,55 +1,606 @@
 package form
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/makeitchaccha/ringring/pkg/middleware"
+)
+
+const (
+	DefaultTTL = 30 * time.Minute
+	// interactionTokenTTL is how long the interaction token can edit the response
+	interactionTokenTTL = 15 * time.Minute
+	// sweepInterval is the interval to look for the expired forms
+	sweepInterval = time.Minute
 )
 
 type Manager interface {
 	// TODO: better name
 	Send(channelID snowflake.ID, form Form) error
 
+	// Reply sends the form as the ephemeral response of the interaction,
+	// so only the user of the interaction can see it.
+	Reply(interaction Interaction, form Form) error
+
+	// Register registers the factory to restore the persistent forms of the kind
+	Register(kind string, factory Factory)
+
+	// Use adds the middlewares wrapping the handlers of the forms, the first one is the outermost
+	Use(middlewares ...middleware.Middleware)
+
 	// as same as the command manager, we need to handle the interaction
 	OnComponentInteractionCreate(event *events.ComponentInteractionCreate)
+	// OnModalSubmitInteractionCreate routes the modal submissions to the form which opened the modal
+	OnModalSubmitInteractionCreate(event *events.ModalSubmitInteractionCreate)
+
+	// Close stops expiring the forms
+	Close()
+}
+
+// Interaction is the interaction which the form can be the response of.
+type Interaction interface {
+	ApplicationID() snowflake.ID
+	Token() string
+	CreateMessage(messageCreate discord.MessageCreate, opts ...rest.RequestOpt) error
+}
+
+// interaction is the interaction with the form
+type interaction interface {
+	Interaction
+	User() discord.User
+	Locale() discord.Locale
 }
 
 var _ Manager = (*managerImpl)(nil)
 
+// entry is the form sent as the message
+type entry struct {
+	// busy is held while the form handles an interaction, so the handlers of a form never run concurrently.
+	busy sync.Mutex
+
+	form      Form
+	channelID snowflake.ID
+	// ephemeral is set if the form is an ephemeral response,
+	// which can only be edited with the latest interaction token.
+	ephemeral bool
+
+	// the fields below are guarded by mu of the manager.
+
+	// applicationID and token are of the latest interaction on the ephemeral form.
+	// they are only kept in memory, so they are empty after restarts until the next interaction.
+	applicationID snowflake.ID
+	token         string
+	expiresAt     time.Time
+}
+
 type managerImpl struct {
-	rest  rest.Channels
-	forms map[snowflake.ID]Form // map of message ID to form
+	rest      rest.Rest
+	store     Store
+	ttl       time.Duration
+	expired   func(locale discord.Locale) string
+	rejected  func(locale discord.Locale) string
+	factories map[string]Factory
+
+	middlewares []middleware.Middleware
+
+	mu    sync.Mutex
+	forms map[snowflake.ID]*entry // map of message ID to form
+
+	done chan struct{}
+}
+
+type ManagerOpt func(*managerImpl)
+
+// WithStore persists the forms into the store, so they keep working after restarts.
+func WithStore(store Store) ManagerOpt {
+	return func(m *managerImpl) {
+		m.store = store
+	}
+}
+
+// WithTTL sets how long the forms accept interactions after the last one.
+// forms implementing Expirable override it.
+func WithTTL(ttl time.Duration) ManagerOpt {
+	return func(m *managerImpl) {
+		m.ttl = ttl
+	}
 }
 
-func NewManager(rest rest.Channels) Manager {
-	return &managerImpl{
-		rest:  rest,
-		forms: make(map[snowflake.ID]Form),
+// WithExpiredMessage sets the message responded when the form is expired or cannot be restored.
+func WithExpiredMessage(expired func(locale discord.Locale) string) ManagerOpt {
+	return func(m *managerImpl) {
+		m.expired = expired
 	}
 }
 
+// WithRejectedMessage sets the message responded when the interaction is on a disabled field or out of the options,
+// e.g. from a client showing the form before it is updated.
+func WithRejectedMessage(rejected func(locale discord.Locale) string) ManagerOpt {
+	return func(m *managerImpl) {
+		m.rejected = rejected
+	}
+}
+
+func NewManager(rest rest.Rest, opts ...ManagerOpt) Manager {
+	m := &managerImpl{
+		rest: rest,
+		ttl:  DefaultTTL,
+		expired: func(discord.Locale) string {
+			return "This form has expired, please run the command again."
+		},
+		rejected: func(discord.Locale) string {
+			return "This option is not available now, please reload the form."
+		},
+		factories: make(map[string]Factory),
+		forms:     make(map[snowflake.ID]*entry),
+		done:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	go m.sweep()
+
+	return m
+}
+
+func (m *managerImpl) Register(kind string, factory Factory) {
+	m.factories[kind] = factory
+}
+
+func (m *managerImpl) Use(middlewares ...middleware.Middleware) {
+	m.middlewares = append(m.middlewares, middlewares...)
+}
+
+func (m *managerImpl) Close() {
+	close(m.done)
+}
+
 func (m *managerImpl) Send(channelID snowflake.ID, form Form) error {
 	msg, err := m.rest.CreateMessage(channelID, form.Create())
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
 	}
 
-	m.forms[msg.ID] = form
+	e := &entry{form: form, channelID: channelID}
+	e.expiresAt = time.Now().Add(m.ttlOf(e))
+	m.add(msg.ID, e)
 	return nil
 }
 
+func (m *managerImpl) Reply(interaction Interaction, form Form) error {
+	create := form.Create()
+	create.Flags = create.Flags.Add(discord.MessageFlagEphemeral)
+
+	if err := interaction.CreateMessage(create); err != nil {
+		return fmt.Errorf("failed to respond form: %w", err)
+	}
+
+	// the message id is needed to route the interactions on the response
+	msg, err := m.rest.GetInteractionResponse(interaction.ApplicationID(), interaction.Token())
+	if err != nil {
+		return fmt.Errorf("failed to get response: %w", err)
+	}
+
+	e := &entry{
+		form:          form,
+		channelID:     msg.ChannelID,
+		ephemeral:     true,
+		applicationID: interaction.ApplicationID(),
+		token:         interaction.Token(),
+	}
+	e.expiresAt = time.Now().Add(m.ttlOf(e))
+	m.add(msg.ID, e)
+	return nil
+}
+
+// add starts routing the interactions on the message to the form
+func (m *managerImpl) add(messageID snowflake.ID, e *entry) {
+	// the interactions wait until the form is persisted
+	e.busy.Lock()
+	defer e.busy.Unlock()
+
+	m.mu.Lock()
+	m.forms[messageID] = e
+	m.mu.Unlock()
+
+	m.persist(messageID, e)
+}
+
 func (m *managerImpl) OnComponentInteractionCreate(event *events.ComponentInteractionCreate) {
-	form, ok := m.forms[event.Message.ID]
+	e, ok := m.prepare(event.Message.ID, event.Message.ChannelID, event)
 	if !ok {
 		return
 	}
 
-	if err := form.Handle(event); err != nil {
-		event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Failed to handle interaction: %v", err),
-		})
+	m.handle(event, func() error {
+		return e.form.Handle(event)
+	})
+
+	m.handled(event.Message.ID, e)
+}
+
+func (m *managerImpl) OnModalSubmitInteractionCreate(event *events.ModalSubmitInteractionCreate) {
+	// the modal is not opened from a form
+	if event.Message == nil {
+		return
+	}
+
+	e, ok := m.prepare(event.Message.ID, event.Message.ChannelID, event)
+	if !ok {
+		return
+	}
+
+	mf, ok := e.form.(ModalForm)
+	if !ok {
+		e.busy.Unlock()
+		fmt.Fprintln(os.Stderr, "modal submitted to the form without modals")
+		return
+	}
+
+	m.handle(event, func() error {
+		return mf.HandleModal(event)
+	})
+
+	m.handled(event.Message.ID, e)
+}
+
+// handle runs the handler through the middlewares,
+// and responds the error not handled by them without the details unless it is for the user.
+func (m *managerImpl) handle(event middleware.Event, handler func() error) {
+	err := middleware.Chain(func(middleware.Event) error {
+		err := handler()
+		// the stale clients are told why, without being reported as the failures
+		if errors.Is(err, ErrFieldDisabled) || errors.Is(err, ErrInvalidOption) {
+			return middleware.WrapUserError(m.rejected(event.Locale()), err)
+		}
+		return err
+	}, m.middlewares...)(event)
+	if err == nil {
+		return
+	}
+
+	message := "Failed to handle interaction"
+	var userErr *middleware.UserError
+	if errors.As(err, &userErr) {
+		message = userErr.Message
+	} else {
+		fmt.Fprintln(os.Stderr, "failed to handle interaction:", err)
+	}
+
+	event.CreateMessage(discord.NewMessageCreateBuilder().
+		SetContent(message).
+		SetEphemeral(true).
+		Build(),
+	)
+}
+
+// prepare finds the form of the message and checks the user can interact with it.
+// if not, the reason is responded to the interaction.
+// the form is locked until handled is called, so the caller must call it if prepared.
+func (m *managerImpl) prepare(messageID, channelID snowflake.ID, i interaction) (*entry, bool) {
+	e, ok := m.find(messageID, channelID)
+	if ok {
+		e.busy.Lock()
+		// the form may be finished or expired while waiting for the other interaction
+		current, expired := m.state(messageID, e, time.Now())
+		if current && expired {
+			m.expire(messageID, e)
+		}
+		if !current || expired {
+			e.busy.Unlock()
+			ok = false
+		}
+	}
+	if !ok {
+		i.CreateMessage(discord.NewMessageCreateBuilder().
+			SetContent(m.expired(i.Locale())).
+			SetEphemeral(true).
+			Build(),
+		)
+		return nil, false
+	}
+
+	if g, ok := e.form.(Guarded); ok {
+		if err := g.Authorize(i.User()); err != nil {
+			e.busy.Unlock()
+			i.CreateMessage(discord.NewMessageCreateBuilder().
+				SetContent(err.Error()).
+				SetEphemeral(true).
+				Build(),
+			)
+			return nil, false
+		}
+	}
+
+	// the latest token is valid for longer
+	m.mu.Lock()
+	if e.ephemeral {
+		e.applicationID = i.ApplicationID()
+		e.token = i.Token()
+	}
+	m.mu.Unlock()
+
+	return e, true
+}
+
+// handled forgets the form if it is finished, or extends the expiry otherwise, then unlocks the form
+func (m *managerImpl) handled(messageID snowflake.ID, e *entry) {
+	defer e.busy.Unlock()
+
+	if f, ok := e.form.(Finishable); ok && f.IsFinished() {
+		m.remove(messageID)
+		return
+	}
+
+	m.mu.Lock()
+	e.expiresAt = time.Now().Add(m.ttlOf(e))
+	m.mu.Unlock()
+
+	m.persist(messageID, e)
+}
+
+// state reports whether the entry is still the form of the message, and whether it is expired at now
+func (m *managerImpl) state(messageID snowflake.ID, e *entry, now time.Time) (current, expired bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.forms[messageID] == e, now.After(e.expiresAt)
+}
+
+// ttlOf must be called with mu held once the entry is added
+func (m *managerImpl) ttlOf(e *entry) time.Duration {
+	ttl := m.ttl
+	if ex, ok := e.form.(Expirable); ok {
+		ttl = ex.TTL()
+	}
+	// the response cannot be disabled once the token is expired
+	if e.ephemeral && ttl > interactionTokenTTL {
+		ttl = interactionTokenTTL
+	}
+	return ttl
+}
+
+// find returns the form of the message, restoring it from the store if it is not in memory
+func (m *managerImpl) find(messageID, channelID snowflake.ID) (*entry, bool) {
+	m.mu.Lock()
+	e, ok := m.forms[messageID]
+	m.mu.Unlock()
+	if ok {
+		return e, true
+	}
+
+	if m.store == nil {
+		return nil, false
+	}
+
+	// the store is not read under the lock, so that the other forms are not blocked by it
+	record, err := m.store.Load(messageID)
+	if err != nil {
+		if !errors.Is(err, ErrNotStored) {
+			fmt.Fprintln(os.Stderr, "failed to load form:", err)
+		}
+		return nil, false
+	}
+
+	form, err := m.restore(record)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to restore form:", err)
+		return nil, false
+	}
+
+	restored := &entry{
+		form:      form,
+		channelID: channelID,
+		ephemeral: record.Ephemeral,
+		expiresAt: record.ExpiresAt,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the form may be restored by the other interaction in the meantime
+	if e, ok := m.forms[messageID]; ok {
+		return e, true
+	}
+	m.forms[messageID] = restored
+	return restored, true
+}
+
+func (m *managerImpl) restore(record Record) (Form, error) {
+	factory, ok := m.factories[record.Kind]
+	if !ok {
+		return nil, fmt.Errorf("no factory registered for %s", record.Kind)
+	}
+	return factory(record.Data)
+}
+
+// persist stores the current state of the form, if it is persistent.
+// the form must be locked by the caller.
+func (m *managerImpl) persist(messageID snowflake.ID, e *entry) {
+	p, ok := e.form.(Persistent)
+	if !ok || m.store == nil {
+		return
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to marshal form:", err)
+		return
+	}
+
+	m.mu.Lock()
+	record := Record{
+		MessageID: messageID,
+		ChannelID: e.channelID,
+		Ephemeral: e.ephemeral,
+		Kind:      p.Kind(),
+		Data:      data,
+		ExpiresAt: e.expiresAt,
+	}
+	m.mu.Unlock()
+
+	if err := m.store.Save(record); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (m *managerImpl) remove(messageID snowflake.ID) {
+	m.mu.Lock()
+	delete(m.forms, messageID)
+	m.mu.Unlock()
+
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Delete(messageID); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// expire disables the components of the form and forgets it.
+// the form must be locked by the caller.
+func (m *managerImpl) expire(messageID snowflake.ID, e *entry) {
+	m.remove(messageID)
+
+	m.mu.Lock()
+	applicationID, token := e.applicationID, e.token
+	m.mu.Unlock()
+
+	update := discord.NewMessageUpdateBuilder()
+	if e.form != nil {
+		msg := e.form.Create()
+		update.SetEmbeds(msg.Embeds...).
+			SetContainerComponents(disableComponents(msg.Components)...)
+	} else {
+		update.SetContainerComponents()
+	}
+
+	var err error
+	if e.ephemeral {
+		// the token is not stored, so the response is left as is after restarts
+		if token == "" {
+			return
+		}
+		_, err = m.rest.UpdateInteractionResponse(applicationID, token, update.Build())
+	} else {
+		_, err = m.rest.UpdateMessage(e.channelID, messageID, update.Build())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to expire form:", err)
+	}
+}
+
+// sweep expires the forms periodically until the manager is closed
+func (m *managerImpl) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.expireAll(now)
+		}
+	}
+}
+
+func (m *managerImpl) expireAll(now time.Time) {
+	expired := make(map[snowflake.ID]*entry)
+
+	m.mu.Lock()
+	for id, e := range m.forms {
+		if now.After(e.expiresAt) {
+			expired[id] = e
+		}
+	}
+	m.mu.Unlock()
+
+	for id, e := range expired {
+		// the interaction being handled may extend the expiry
+		e.busy.Lock()
+		if current, stillExpired := m.state(id, e, now); current && stillExpired {
+			m.expire(id, e)
+		}
+		e.busy.Unlock()
+	}
+
+	// the forms not restored since the restart
+	if m.store != nil {
+		records, err := m.store.Expired(now)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		for _, record := range records {
+			// the forms restored since are expired by their own expiry
+			if _, ok := expired[record.MessageID]; ok || m.restored(record.MessageID) {
+				continue
+			}
+			form, err := m.restore(record)
+			if err != nil {
+				// the components are just removed
+				form = nil
+			}
+			// nobody else knows the entry, so it is not locked
+			m.expire(record.MessageID, &entry{
+				form:      form,
+				channelID: record.ChannelID,
+				ephemeral: record.Ephemeral,
+				expiresAt: record.ExpiresAt,
+			})
+		}
+	}
+}
+
+func (m *managerImpl) restored(messageID snowflake.ID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.forms[messageID]
+	return ok
+}
+
+// disableComponents returns the components with every interactive component disabled
+func disableComponents(components []discord.ContainerComponent) []discord.ContainerComponent {
+	disabled := make([]discord.ContainerComponent, 0, len(components))
+	for _, c := range components {
+		row, ok := c.(discord.ActionRowComponent)
+		if !ok {
+			disabled = append(disabled, c)
+			continue
+		}
+
+		newRow := make(discord.ActionRowComponent, 0, len(row))
+		for _, ic := range row {
+			switch v := ic.(type) {
+			case discord.ButtonComponent:
+				// link buttons keep working
+				if v.Style != discord.ButtonStyleLink {
+					ic = v.AsDisabled()
+				}
+			case discord.StringSelectMenuComponent:
+				ic = v.AsDisabled()
+			case discord.UserSelectMenuComponent:
+				ic = v.AsDisabled()
+			case discord.RoleSelectMenuComponent:
+				ic = v.AsDisabled()
+			case discord.MentionableSelectMenuComponent:
+				ic = v.AsDisabled()
+			case discord.ChannelSelectMenuComponent:
+				ic = v.AsDisabled()
+			}
+			newRow = append(newRow, ic)
+		}
+		disabled = append(disabled, newRow)
 	}
+	return disabled
 }
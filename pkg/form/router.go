@@ -0,0 +1,199 @@
+package form
+
+import (
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+)
+
+const (
+	confirmSuffix = ":confirm"
+	cancelSuffix  = ":cancel"
+	pagePrefix    = "page:"
+)
+
+// Field is a component of the form, routed by the custom id.
+// buttons are placed in a row in the order they are added,
+// and every select menu gets its own row.
+type Field interface {
+	CustomID() string
+	inline() bool
+	// disabled fields refuse the interactions
+	disabled() bool
+	// component returns nil if the field is hidden
+	component() discord.InteractiveComponent
+	handle(r *Router, event *events.ComponentInteractionCreate) error
+}
+
+// Page is a group of the fields shown together.
+// the state of the form is shared among the pages.
+type Page struct {
+	ID     string
+	Label  string
+	Fields []Field
+}
+
+// Router builds the components of the form from the fields,
+// and routes the interactions to them.
+type Router struct {
+	render  func(status string) discord.MessageUpdate
+	pending *string
+	fields  []Field
+
+	page  *string
+	pages []Page
+}
+
+// NewRouter creates the router.
+// render renders the whole form with the status, after a field is changed.
+// pending keeps the custom id of the button waiting for the confirmation,
+// it should be a part of the form state so the confirmation survives restarts.
+func NewRouter(render func(status string) discord.MessageUpdate, pending *string) *Router {
+	return &Router{
+		render:  render,
+		pending: pending,
+	}
+}
+
+// Add adds the fields shown on every page.
+func (r *Router) Add(fields ...Field) *Router {
+	r.fields = append(r.fields, fields...)
+	return r
+}
+
+// Pages splits the rest of the fields into the pages, navigated with the buttons.
+// page keeps the id of the current page, the first page is shown if it is empty.
+// the page takes 1 row for the navigation, and its buttons and select menus should fit in the rest.
+func (r *Router) Pages(page *string, pages ...Page) *Router {
+	r.page = page
+	r.pages = pages
+	return r
+}
+
+func (r *Router) current() (Page, bool) {
+	if len(r.pages) == 0 {
+		return Page{}, false
+	}
+	for _, p := range r.pages {
+		if p.ID == *r.page {
+			return p, true
+		}
+	}
+	return r.pages[0], true
+}
+
+// visible returns the fields on the current page
+func (r *Router) visible() []Field {
+	page, ok := r.current()
+	if !ok {
+		return r.fields
+	}
+	return append(append([]Field{}, r.fields...), page.Fields...)
+}
+
+// Components builds the components, or the confirmation if a button is waiting for it.
+func (r *Router) Components() []discord.ContainerComponent {
+	if b, ok := r.confirming(); ok {
+		return []discord.ContainerComponent{
+			discord.NewActionRow(
+				discord.NewButton(b.Confirm.Style, b.Confirm.Label, b.ID+confirmSuffix, "", 0),
+				discord.NewSecondaryButton(b.Confirm.Cancel, b.ID+cancelSuffix),
+			),
+		}
+	}
+
+	rows := layout(r.fields)
+
+	page, ok := r.current()
+	if !ok {
+		return rows
+	}
+
+	navigation := discord.NewActionRow()
+	for _, p := range r.pages {
+		button := discord.NewSecondaryButton(p.Label, pagePrefix+p.ID)
+		if p.ID == page.ID {
+			button = discord.NewPrimaryButton(p.Label, pagePrefix+p.ID).AsDisabled()
+		}
+		navigation = navigation.AddComponents(button)
+	}
+
+	rows = append(rows, navigation)
+	return append(rows, layout(page.Fields)...)
+}
+
+// layout places the buttons in the first row, and the select menus in the following rows
+func layout(fields []Field) []discord.ContainerComponent {
+	buttons := discord.NewActionRow()
+	rows := []discord.ContainerComponent{}
+
+	for _, f := range fields {
+		c := f.component()
+		if c == nil {
+			continue
+		}
+		if f.inline() {
+			buttons = buttons.AddComponents(c)
+		} else {
+			rows = append(rows, discord.NewActionRow(c))
+		}
+	}
+
+	if len(buttons) > 0 {
+		rows = append([]discord.ContainerComponent{buttons}, rows...)
+	}
+	return rows
+}
+
+// Handle routes the interaction to the field, and reports if any field handled it.
+func (r *Router) Handle(event *events.ComponentInteractionCreate) (bool, error) {
+	customID := event.Data.CustomID()
+
+	if b, ok := r.confirming(); ok {
+		switch customID {
+		case b.ID + confirmSuffix:
+			*r.pending = ""
+			if b.Disabled {
+				return true, ErrFieldDisabled
+			}
+			if b.Guard != nil {
+				if err := b.Guard(); err != nil {
+					return true, event.UpdateMessage(r.render(err.Error()))
+				}
+			}
+			return true, b.Handler(event)
+		case b.ID + cancelSuffix:
+			*r.pending = ""
+			return true, event.UpdateMessage(r.render(""))
+		}
+		return false, nil
+	}
+
+	if id, ok := strings.CutPrefix(customID, pagePrefix); ok && r.page != nil {
+		*r.page = id
+		return true, event.UpdateMessage(r.render(""))
+	}
+
+	for _, f := range r.visible() {
+		if f.CustomID() == customID && f.component() != nil {
+			if f.disabled() {
+				return true, ErrFieldDisabled
+			}
+			return true, f.handle(r, event)
+		}
+	}
+	return false, nil
+}
+
+func (r *Router) confirming() (*Button, bool) {
+	if r.pending == nil || *r.pending == "" {
+		return nil, false
+	}
+	for _, f := range r.visible() {
+		if b, ok := f.(*Button); ok && b.ID == *r.pending && b.Confirm != nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,121 @@
+package form
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"gorm.io/gorm"
+)
+
+var ErrNotStored = errors.New("form is not stored")
+
+// Record is the persisted state of the form sent as the message.
+type Record struct {
+	MessageID snowflake.ID
+	ChannelID snowflake.ID
+	// Ephemeral is set if the form is an ephemeral response,
+	// which can only be edited with the interaction token kept in memory.
+	Ephemeral bool
+	Kind      string
+	Data      []byte
+	ExpiresAt time.Time
+}
+
+// Store persists the state of the forms by the message ID,
+// so that the forms can be restored after the bot restarts.
+type Store interface {
+	Save(record Record) error
+	// Load returns ErrNotStored if no form is stored for the message
+	Load(messageID snowflake.ID) (Record, error)
+	Delete(messageID snowflake.ID) error
+	// Expired returns the records expired at the time
+	Expired(now time.Time) ([]Record, error)
+}
+
+type FormModel struct {
+	MessageID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	ChannelID uint64
+	Ephemeral bool
+	Kind      string
+	Data      []byte
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (m FormModel) toRecord() Record {
+	return Record{
+		MessageID: snowflake.ID(m.MessageID),
+		ChannelID: snowflake.ID(m.ChannelID),
+		Ephemeral: m.Ephemeral,
+		Kind:      m.Kind,
+		Data:      m.Data,
+		ExpiresAt: m.ExpiresAt,
+	}
+}
+
+var _ Store = (*dbStore)(nil)
+
+type dbStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) Store {
+	db.AutoMigrate(&FormModel{})
+	// the interaction tokens were stored by the older versions
+	for _, column := range []string{"application_id", "token"} {
+		if db.Migrator().HasColumn(&FormModel{}, column) {
+			db.Migrator().DropColumn(&FormModel{}, column)
+		}
+	}
+
+	return &dbStore{
+		db: db,
+	}
+}
+
+func (s *dbStore) Save(record Record) error {
+	model := FormModel{
+		MessageID: uint64(record.MessageID),
+		ChannelID: uint64(record.ChannelID),
+		Ephemeral: record.Ephemeral,
+		Kind:      record.Kind,
+		Data:      record.Data,
+		ExpiresAt: record.ExpiresAt,
+	}
+	if err := s.db.Save(&model).Error; err != nil {
+		return fmt.Errorf("failed to save form: %w", err)
+	}
+	return nil
+}
+
+func (s *dbStore) Load(messageID snowflake.ID) (Record, error) {
+	var model FormModel
+	if err := s.db.First(&model, "message_id = ?", uint64(messageID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Record{}, ErrNotStored
+		}
+		return Record{}, fmt.Errorf("failed to load form: %w", err)
+	}
+	return model.toRecord(), nil
+}
+
+func (s *dbStore) Delete(messageID snowflake.ID) error {
+	if err := s.db.Delete(&FormModel{}, "message_id = ?", uint64(messageID)).Error; err != nil {
+		return fmt.Errorf("failed to delete form: %w", err)
+	}
+	return nil
+}
+
+func (s *dbStore) Expired(now time.Time) ([]Record, error) {
+	var models []FormModel
+	if err := s.db.Find(&models, "expires_at <= ?", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to find expired forms: %w", err)
+	}
+
+	records := make([]Record, len(models))
+	for i, m := range models {
+		records[i] = m.toRecord()
+	}
+	return records, nil
+}
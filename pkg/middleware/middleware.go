@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/rest"
+)
+
+// Event is the interaction the handlers and the middlewares work on.
+// commands, components and modals are all events.
+type Event interface {
+	User() discord.User
+	Member() *discord.ResolvedMember
+	Locale() discord.Locale
+	CreateMessage(messageCreate discord.MessageCreate, opts ...rest.RequestOpt) error
+}
+
+type Handler func(event Event) error
+
+// Middleware wraps the handler to do something before and after it.
+type Middleware func(next Handler) Handler
+
+// Chain wraps the handler with the middlewares, the first middleware is the outermost.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// UserError is an error which can be shown to the user as is.
+// other errors are never shown, as they may contain internal details.
+type UserError struct {
+	// Message should be localized for the user
+	Message string
+	Err     error
+}
+
+func NewUserError(message string) error {
+	return &UserError{Message: message}
+}
+
+// WrapUserError shows the message to the user, and keeps err for logging.
+func WrapUserError(message string, err error) error {
+	return &UserError{Message: message, Err: err}
+}
+
+func (e *UserError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// describe returns what the event is for logging
+func describe(event Event) string {
+	switch e := event.(type) {
+	case *events.ApplicationCommandInteractionCreate:
+		return "command " + e.Data.CommandName()
+	case *events.ComponentInteractionCreate:
+		return "component " + e.Data.CustomID()
+	case *events.ModalSubmitInteractionCreate:
+		return "modal " + e.Data.CustomID
+	}
+	return "interaction"
+}
+
+// Recover converts a panic in the handler into an error.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(event Event) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Fprintf(os.Stderr, "panic in %s: %v\n%s", describe(event), r, debug.Stack())
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			return next(event)
+		}
+	}
+}
+
+// Log logs the errors of the handler, except the ones for the user.
+func Log() Middleware {
+	return func(next Handler) Handler {
+		return func(event Event) error {
+			err := next(event)
+			var userErr *UserError
+			if err != nil && !errors.As(err, &userErr) {
+				fmt.Fprintf(os.Stderr, "failed to handle %s by %s: %v\n", describe(event), event.User().ID, err)
+			}
+			return err
+		}
+	}
+}
+
+// Respond responds the error to the user ephemerally.
+// the message of UserError is shown, and internal returns the message for the other errors.
+func Respond(internal func(locale discord.Locale) string) Middleware {
+	return func(next Handler) Handler {
+		return func(event Event) error {
+			err := next(event)
+			if err == nil {
+				return nil
+			}
+
+			message := internal(event.Locale())
+			var userErr *UserError
+			if errors.As(err, &userErr) {
+				message = userErr.Message
+			}
+
+			// the interaction may be already responded
+			if respondErr := event.CreateMessage(discord.NewMessageCreateBuilder().
+				SetContent(message).
+				SetEphemeral(true).
+				Build(),
+			); respondErr != nil {
+				fmt.Fprintln(os.Stderr, "failed to respond error:", respondErr)
+			}
+			return nil
+		}
+	}
+}
+
+// RequirePermissions rejects the users without the permissions, and the events outside the guilds.
+func RequirePermissions(permissions discord.Permissions, message func(locale discord.Locale) string) Middleware {
+	return func(next Handler) Handler {
+		return func(event Event) error {
+			member := event.Member()
+			if member == nil || !member.Permissions.Has(permissions) {
+				return NewUserError(message(event.Locale()))
+			}
+			return next(event)
+		}
+	}
+}
+
+// Cooldown rejects the events of the same user and key within the duration.
+// key groups the events, e.g. by the command name.
+func Cooldown(duration time.Duration, key func(event Event) string, message func(locale discord.Locale, remaining time.Duration) string) Middleware {
+	type cooldownKey struct {
+		user string
+		key  string
+	}
+
+	var (
+		mu   sync.Mutex
+		last = make(map[cooldownKey]time.Time)
+	)
+
+	return func(next Handler) Handler {
+		return func(event Event) error {
+			k := cooldownKey{user: event.User().ID.String(), key: key(event)}
+			now := time.Now()
+
+			mu.Lock()
+			if t, ok := last[k]; ok && now.Sub(t) < duration {
+				mu.Unlock()
+				return NewUserError(message(event.Locale(), duration-now.Sub(t)))
+			}
+			last[k] = now
+			// forget the users who are not in the cooldown anymore
+			for k, t := range last {
+				if now.Sub(t) >= duration {
+					delete(last, k)
+				}
+			}
+			mu.Unlock()
+
+			return next(event)
+		}
+	}
+}
+
+// CommandName is the key of Cooldown to limit each command separately.
+func CommandName(event Event) string {
+	if e, ok := event.(*events.ApplicationCommandInteractionCreate); ok {
+		return e.Data.CommandName()
+	}
+	return describe(event)
+}